@@ -0,0 +1,135 @@
+package redis
+
+import (
+	"math/big"
+
+	"github.com/Avik32223/redis-server/internal/rediserr"
+)
+
+// bigNumberCommands work on strings holding decimal integers of any size,
+// stored the way INCR stores them, so both apply to the same keys.
+// They reply with big numbers, or with bulk strings to RESP2 clients.
+// They are this server's own, not redis commands: they have a group of
+// their own, and no redis version they appeared in.
+var bigNumberCommands = []*commandSpec{
+	{
+		name: "bigget", run: bigget, arity: 2,
+		flags:    []string{flagReadonly, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RO", "ACCESS"},
+		categories: []string{"@read", "@string", "@fast"},
+		group:      "bignum", complexity: "O(N) where N is the number of digits of the value.",
+		summary: "Returns the arbitrary precision integer value of a key. Not part of Redis.",
+	},
+	{
+		name: "bigincr", run: bigincr, arity: 2,
+		flags:    []string{flagWrite, flagDenyOOM, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "UPDATE"},
+		categories: []string{"@write", "@string", "@fast"},
+		group:      "bignum", complexity: "O(N) where N is the number of digits of the value.",
+		summary: "Increments the arbitrary precision integer value of a key by one. Uses 0 as initial value if the key doesn't exist. Not part of Redis.",
+	},
+	{
+		name: "bigdecr", run: bigdecr, arity: 2,
+		flags:    []string{flagWrite, flagDenyOOM, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "UPDATE"},
+		categories: []string{"@write", "@string", "@fast"},
+		group:      "bignum", complexity: "O(N) where N is the number of digits of the value.",
+		summary: "Decrements the arbitrary precision integer value of a key by one. Uses 0 as initial value if the key doesn't exist. Not part of Redis.",
+	},
+	{
+		name: "bigincrby", run: bigincrby, arity: 3,
+		flags:    []string{flagWrite, flagDenyOOM, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "UPDATE"},
+		categories: []string{"@write", "@string", "@fast"},
+		group:      "bignum", complexity: "O(N) where N is the number of digits of the value.",
+		summary: "Increments the arbitrary precision integer value of a key by a number. Uses 0 as initial value if the key doesn't exist. Not part of Redis.",
+	},
+	{
+		name: "bigdecrby", run: bigdecrby, arity: 3,
+		flags:    []string{flagWrite, flagDenyOOM, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "UPDATE"},
+		categories: []string{"@write", "@string", "@fast"},
+		group:      "bignum", complexity: "O(N) where N is the number of digits of the value.",
+		summary: "Decrements the arbitrary precision integer value of a key by a number. Uses 0 as initial value if the key doesn't exist. Not part of Redis.",
+	},
+}
+
+// parseBigInt parses s as an integer of any size, with the syntax
+// parseInt accepts: in base 10, without leading zeros, plus sign or spaces.
+func parseBigInt(s string) (*big.Int, error) {
+	digits := s
+	if len(digits) > 0 && digits[0] == '-' {
+		digits = digits[1:]
+	}
+	if len(digits) == 0 || (digits[0] == '0' && len(s) > 1) {
+		return nil, rediserr.NotInteger
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return nil, rediserr.NotInteger
+		}
+	}
+	n, _ := new(big.Int).SetString(s, 10)
+	return n, nil
+}
+
+func bigget(c *Client, ca ...any) (Reply, error) {
+	v, found, err := lookupString(c.db(), ca[0].(string))
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return NullBulkReply{}, nil
+	}
+	n, err := parseBigInt(v)
+	if err != nil {
+		return nil, err
+	}
+	return BigNumberReply{Int: n}, nil
+}
+
+func bigincr(c *Client, ca ...any) (Reply, error) {
+	return bigIncrBy(c.db(), ca[0].(string), big.NewInt(1))
+}
+
+func bigdecr(c *Client, ca ...any) (Reply, error) {
+	return bigIncrBy(c.db(), ca[0].(string), big.NewInt(-1))
+}
+
+func bigincrby(c *Client, ca ...any) (Reply, error) {
+	by, err := parseBigInt(ca[1].(string))
+	if err != nil {
+		return nil, err
+	}
+	return bigIncrBy(c.db(), ca[0].(string), by)
+}
+
+func bigdecrby(c *Client, ca ...any) (Reply, error) {
+	by, err := parseBigInt(ca[1].(string))
+	if err != nil {
+		return nil, err
+	}
+	return bigIncrBy(c.db(), ca[0].(string), by.Neg(by))
+}
+
+// bigIncrBy adds by to the integer stored at key, 0 if key does not exist.
+// The expiry of key is kept.
+func bigIncrBy(s State, key string, by *big.Int) (Reply, error) {
+	v, found, err := lookupString(s, key)
+	if err != nil {
+		return nil, err
+	}
+	n := new(big.Int)
+	if found {
+		if n, err = parseBigInt(v); err != nil {
+			return nil, err
+		}
+	}
+	n.Add(n, by)
+	d := n.String()
+	if len(d) > maxBulkLen {
+		return nil, errorStringTooLong
+	}
+	setString(s, key, d)
+	return BigNumberReply{Int: n}, nil
+}
@@ -0,0 +1,558 @@
+package redis
+
+import (
+	"math"
+	"math/bits"
+	"strings"
+
+	"github.com/Avik32223/redis-server/internal/rediserr"
+)
+
+var bitmapCommands = []*commandSpec{
+	{
+		name: "setbit", run: setbit, arity: 4,
+		flags:    []string{flagWrite, flagDenyOOM},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "UPDATE"},
+		categories: []string{"@write", "@bitmap", "@slow"},
+		group:      "bitmap", since: "2.2.0", complexity: "O(1)",
+		summary: "Sets or clears the bit at offset of the string value. Creates the key if it doesn't exist.",
+	},
+	{
+		name: "getbit", run: getbit, arity: 3,
+		flags:    []string{flagReadonly, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RO", "ACCESS"},
+		categories: []string{"@read", "@bitmap", "@fast"},
+		group:      "bitmap", since: "2.2.0", complexity: "O(1)",
+		summary: "Returns a bit value by offset.",
+	},
+	{
+		name: "bitcount", run: bitcount, arity: -2,
+		flags:    []string{flagReadonly},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RO", "ACCESS"},
+		categories: []string{"@read", "@bitmap", "@slow"},
+		group:      "bitmap", since: "2.6.0", complexity: "O(N)",
+		summary: "Counts the number of set bits (population counting) in a string.",
+	},
+	{
+		name: "bitpos", run: bitpos, arity: -3,
+		flags:    []string{flagReadonly},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RO", "ACCESS"},
+		categories: []string{"@read", "@bitmap", "@slow"},
+		group:      "bitmap", since: "2.8.7", complexity: "O(N)",
+		summary: "Finds the first set (1) or clear (0) bit in a string.",
+	},
+	{
+		name: "bitop", run: bitop, arity: -4,
+		flags:    []string{flagWrite, flagDenyOOM},
+		firstKey: 2, lastKey: -1, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "UPDATE"},
+		categories: []string{"@write", "@bitmap", "@slow"},
+		group:      "bitmap", since: "2.6.0", complexity: "O(N)",
+		summary: "Performs bitwise operations on multiple strings, and stores the result.",
+	},
+	{
+		name: "bitfield", run: bitfield, arity: -2,
+		flags:    []string{flagWrite, flagDenyOOM},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "UPDATE"},
+		categories: []string{"@write", "@bitmap", "@slow"},
+		group:      "bitmap", since: "3.2.0", complexity: "O(1) for each subcommand specified",
+		summary: "Performs arbitrary bitfield integer operations on strings.",
+	},
+	{
+		name: "bitfield_ro", run: bitfieldRO, arity: -2,
+		flags:    []string{flagReadonly, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RO", "ACCESS"},
+		categories: []string{"@read", "@bitmap", "@fast"},
+		group:      "bitmap", since: "6.0.0", complexity: "O(1) for each subcommand specified",
+		summary: "Performs arbitrary read-only bitfield integer operations on strings.",
+	},
+}
+
+var errorBitOffset = rediserr.New(rediserr.ERR, "bit offset is not an integer or out of range")
+
+// parseBitOffset parses the offset of a bit in a string, which must fit
+// in a string of at most maxBulkLen bytes. With hash, the offset may also
+// be given as #n, the offset of the nth integer of the given width.
+func parseBitOffset(s string, hash bool, width int) (int64, error) {
+	useHash := hash && strings.HasPrefix(s, "#")
+	if useHash {
+		s = s[1:]
+	}
+	offset, err := parseInt(s)
+	if err != nil {
+		return 0, errorBitOffset
+	}
+	if useHash {
+		if offset > math.MaxInt64/int64(width) || offset < math.MinInt64/int64(width) {
+			return 0, errorBitOffset
+		}
+		offset *= int64(width)
+	}
+	if offset < 0 || offset>>3 >= maxBulkLen {
+		return 0, errorBitOffset
+	}
+	return offset, nil
+}
+
+// bitRange converts the start and end offsets of BITCOUNT and BITPOS,
+// counted in bytes or, with BIT, in bits, to the first and last bits they
+// include in a string of l bytes. Negative offsets count from the end of
+// the string. The range is empty if first > last.
+func bitRange(start, end int64, isBit bool, l int) (first, last int64) {
+	total := int64(l)
+	if isBit {
+		total <<= 3
+	}
+	if start < 0 {
+		start = max(total+start, 0)
+	}
+	if end < 0 {
+		end = max(total+end, 0)
+	}
+	end = min(end, total-1)
+	if isBit || start > end {
+		return start, end
+	}
+	return start << 3, end<<3 + 7
+}
+
+// parseBitUnit parses the BYTE or BIT unit of a range.
+func parseBitUnit(s string) (bool, error) {
+	switch strings.ToUpper(s) {
+	case "BIT":
+		return true, nil
+	case "BYTE":
+		return false, nil
+	}
+	return false, rediserr.Syntax
+}
+
+// rangeMask returns the bits of byte i of a string that are in the range
+// of bits from first to last.
+func rangeMask(i, first, last int64) byte {
+	mask := byte(0xff)
+	if i == first>>3 {
+		mask &= 0xff >> (first & 7)
+	}
+	if i == last>>3 {
+		mask &= 0xff << (7 - last&7)
+	}
+	return mask
+}
+
+// setbit sets or clears a bit of a string, growing it with zero bytes if
+// it is too short, and replies with the previous value of the bit.
+func setbit(c *Client, ca ...any) (Reply, error) {
+	s := c.db()
+	key := ca[0].(string)
+	offset, err := parseBitOffset(ca[1].(string), false, 0)
+	if err != nil {
+		return nil, err
+	}
+	on, err := parseInt(ca[2].(string))
+	if err != nil || on&^1 != 0 {
+		return nil, rediserr.New(rediserr.ERR, "bit is not an integer or out of range")
+	}
+	v, _, err := lookupString(s, key)
+	if err != nil {
+		return nil, err
+	}
+
+	b := []byte(v)
+	i := int(offset >> 3)
+	if i >= len(b) {
+		b = append(b, make([]byte, i+1-len(b))...)
+	}
+	mask := byte(0x80) >> (offset & 7)
+	old := 0
+	if b[i]&mask != 0 {
+		old = 1
+	}
+	if on == 1 {
+		b[i] |= mask
+	} else {
+		b[i] &^= mask
+	}
+	setString(s, key, string(b))
+	return IntegerReply(old), nil
+}
+
+func getbit(c *Client, ca ...any) (Reply, error) {
+	offset, err := parseBitOffset(ca[1].(string), false, 0)
+	if err != nil {
+		return nil, err
+	}
+	v, _, err := lookupString(c.db(), ca[0].(string))
+	if err != nil {
+		return nil, err
+	}
+	i := offset >> 3
+	if i >= int64(len(v)) || v[i]&(0x80>>(offset&7)) == 0 {
+		return IntegerReply(0), nil
+	}
+	return IntegerReply(1), nil
+}
+
+// bitcount counts the bits set in a string, or in a range of it.
+func bitcount(c *Client, ca ...any) (Reply, error) {
+	var start, end int64
+	var isBit bool
+	switch len(ca) {
+	case 1:
+	case 3, 4:
+		var err error
+		if start, err = parseInt(ca[1].(string)); err != nil {
+			return nil, err
+		}
+		if end, err = parseInt(ca[2].(string)); err != nil {
+			return nil, err
+		}
+		if len(ca) == 4 {
+			if isBit, err = parseBitUnit(ca[3].(string)); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, rediserr.Syntax
+	}
+	v, found, err := lookupString(c.db(), ca[0].(string))
+	if err != nil {
+		return nil, err
+	}
+	if !found || (start < 0 && end < 0 && start > end) {
+		return IntegerReply(0), nil
+	}
+
+	first, last := int64(0), int64(len(v))<<3-1
+	if len(ca) > 1 {
+		first, last = bitRange(start, end, isBit, len(v))
+	}
+	n := 0
+	for i := first >> 3; first <= last && i <= last>>3; i++ {
+		n += bits.OnesCount8(v[i] & rangeMask(i, first, last))
+	}
+	return IntegerReply(n), nil
+}
+
+// bitpos replies with the position of the first bit set to 1 or 0 in a
+// string, or in a range of it. Missing keys, and the bits past the end of
+// strings when no end is given, count as zeros.
+func bitpos(c *Client, ca ...any) (Reply, error) {
+	bit, err := parseInt(ca[1].(string))
+	if err != nil {
+		return nil, err
+	}
+	if bit != 0 && bit != 1 {
+		return nil, rediserr.New(rediserr.ERR, "The bit argument must be 1 or 0.")
+	}
+	v, found, err := lookupString(c.db(), ca[0].(string))
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return IntegerReply(-bit), nil
+	}
+
+	if len(ca) > 5 {
+		return nil, rediserr.Syntax
+	}
+
+	first, last := int64(0), int64(len(v))<<3-1
+	endGiven := len(ca) > 3
+	if len(ca) > 2 {
+		start, err := parseInt(ca[2].(string))
+		if err != nil {
+			return nil, err
+		}
+		var isBit bool
+		if len(ca) == 5 {
+			if isBit, err = parseBitUnit(ca[4].(string)); err != nil {
+				return nil, err
+			}
+		}
+		end := int64(len(v)) - 1
+		if endGiven {
+			if end, err = parseInt(ca[3].(string)); err != nil {
+				return nil, err
+			}
+		} else if isBit {
+			end = int64(len(v))<<3 - 1
+		}
+		first, last = bitRange(start, end, isBit, len(v))
+	}
+	if first > last {
+		return IntegerReply(-1), nil
+	}
+
+	for i := first >> 3; i <= last>>3; i++ {
+		b := v[i]
+		if bit == 0 {
+			b = ^b
+		}
+		if b &= rangeMask(i, first, last); b != 0 {
+			return IntegerReply(i<<3 + int64(bits.LeadingZeros8(b))), nil
+		}
+	}
+	if bit == 1 || endGiven {
+		return IntegerReply(-1), nil
+	}
+	return IntegerReply(last + 1), nil
+}
+
+// bitop stores the result of a bitwise operation between strings at
+// destkey, and replies with its length. Shorter strings, and missing keys,
+// are padded with zero bytes to the length of the longest.
+func bitop(c *Client, ca ...any) (Reply, error) {
+	s := c.db()
+	op, dest, keys := strings.ToUpper(ca[0].(string)), ca[1].(string), ca[2:]
+	switch op {
+	case "AND", "OR", "XOR":
+	case "NOT":
+		if len(keys) != 1 {
+			return nil, rediserr.New(rediserr.ERR, "BITOP NOT must be called with a single source key.")
+		}
+	default:
+		return nil, rediserr.Syntax
+	}
+
+	srcs := make([]string, len(keys))
+	l := 0
+	for i, key := range keys {
+		v, _, err := lookupString(s, key.(string))
+		if err != nil {
+			return nil, err
+		}
+		srcs[i] = v
+		l = max(l, len(v))
+	}
+	if l == 0 {
+		delete(*s.Data(), dest)
+		return IntegerReply(0), nil
+	}
+
+	res := make([]byte, l)
+	copy(res, srcs[0])
+	for _, src := range srcs[1:] {
+		for i := range res {
+			var b byte
+			if i < len(src) {
+				b = src[i]
+			}
+			switch op {
+			case "AND":
+				res[i] &= b
+			case "OR":
+				res[i] |= b
+			case "XOR":
+				res[i] ^= b
+			}
+		}
+	}
+	if op == "NOT" {
+		for i := range res {
+			res[i] = ^res[i]
+		}
+	}
+	(*s.Data())[dest] = &stateValue{val: string(res)}
+	return IntegerReply(l), nil
+}
+
+// bitfieldOp is a GET, SET or INCRBY operation of BITFIELD on the integer
+// of the given width at offset, with the OVERFLOW behavior in effect.
+type bitfieldOp struct {
+	op       string
+	signed   bool
+	width    int
+	offset   int64
+	value    int64
+	overflow string
+}
+
+// parseBitfieldType parses an integer type of BITFIELD, as i16 or u8.
+func parseBitfieldType(s string) (signed bool, width int, err error) {
+	errType := rediserr.New(rediserr.ERR, "Invalid bitfield type. Use something like i16 u8. Note that u64 is not supported but i64 is.")
+	if len(s) == 0 || (s[0] != 'i' && s[0] != 'u') {
+		return false, 0, errType
+	}
+	signed = s[0] == 'i'
+	n, err := parseInt(s[1:])
+	if err != nil || n < 1 || (signed && n > 64) || (!signed && n > 63) {
+		return false, 0, errType
+	}
+	return signed, int(n), nil
+}
+
+func bitfield(c *Client, ca ...any) (Reply, error) {
+	return bitfieldGeneric(c, false, ca...)
+}
+
+func bitfieldRO(c *Client, ca ...any) (Reply, error) {
+	return bitfieldGeneric(c, true, ca...)
+}
+
+// bitfieldGeneric runs the operations of BITFIELD, or of BITFIELD_RO with
+// readonly, in order, and replies with the result of each. A string is
+// created, or grown with zero bytes, to hold every integer written to.
+func bitfieldGeneric(c *Client, readonly bool, ca ...any) (Reply, error) {
+	s := c.db()
+	key := ca[0].(string)
+	var ops []bitfieldOp
+	overflow := "WRAP"
+	writes, highest := false, int64(0)
+	for i := 1; i < len(ca); i++ {
+		op, more := strings.ToUpper(ca[i].(string)), len(ca)-i-1
+		switch {
+		case op == "GET" && more >= 2, (op == "SET" || op == "INCRBY") && more >= 3:
+		case op == "OVERFLOW" && more >= 1:
+			overflow = strings.ToUpper(ca[i+1].(string))
+			if overflow != "WRAP" && overflow != "SAT" && overflow != "FAIL" {
+				return nil, rediserr.New(rediserr.ERR, "Invalid OVERFLOW type specified")
+			}
+			i++
+			continue
+		default:
+			return nil, rediserr.Syntax
+		}
+
+		signed, width, err := parseBitfieldType(ca[i+1].(string))
+		if err != nil {
+			return nil, err
+		}
+		offset, err := parseBitOffset(ca[i+2].(string), true, width)
+		if err != nil {
+			return nil, err
+		}
+		bop := bitfieldOp{op: op, signed: signed, width: width, offset: offset, overflow: overflow}
+		if op == "GET" {
+			i += 2
+		} else {
+			if bop.value, err = parseInt(ca[i+3].(string)); err != nil {
+				return nil, err
+			}
+			writes, highest = true, max(highest, offset+int64(width)-1)
+			i += 3
+		}
+		ops = append(ops, bop)
+	}
+
+	if writes && readonly {
+		return nil, rediserr.New(rediserr.ERR, "BITFIELD_RO only supports the GET subcommand")
+	}
+	v, _, err := lookupString(s, key)
+	if err != nil {
+		return nil, err
+	}
+	b := []byte(v)
+	if writes && int(highest>>3) >= len(b) {
+		b = append(b, make([]byte, int(highest>>3)+1-len(b))...)
+	}
+
+	res := make(ArrayReply, len(ops))
+	for i, op := range ops {
+		old := getBitfield(b, op.offset, op.width, op.signed)
+		if op.op == "GET" {
+			res[i] = IntegerReply(old)
+			continue
+		}
+		var incr int64
+		newVal, reply := op.value, old
+		if op.op == "INCRBY" {
+			newVal, incr = old, op.value
+		}
+		wrapped, overflows := checkBitfieldOverflow(newVal, incr, op)
+		if overflows && op.overflow == "FAIL" {
+			res[i] = NullBulkReply{}
+			continue
+		}
+		newVal = wrapped
+		if op.op == "INCRBY" {
+			reply = newVal
+		}
+		setBitfield(b, op.offset, op.width, newVal)
+		res[i] = IntegerReply(reply)
+	}
+	if writes {
+		setString(s, key, string(b))
+	}
+	return res, nil
+}
+
+// getBitfield reads the integer of width bits at offset in b, the most
+// significant bit first. Bits past the end of b are zeros.
+func getBitfield(b []byte, offset int64, width int, signed bool) int64 {
+	var v uint64
+	for j := int64(0); j < int64(width); j++ {
+		i, bit := (offset+j)>>3, uint64(0)
+		if i < int64(len(b)) && b[i]&(0x80>>((offset+j)&7)) != 0 {
+			bit = 1
+		}
+		v = v<<1 | bit
+	}
+	if signed && width < 64 && v&(1<<(width-1)) != 0 {
+		v |= math.MaxUint64 << width
+	}
+	return int64(v)
+}
+
+// setBitfield writes the width low bits of v at offset in b,
+// which must be long enough to hold them.
+func setBitfield(b []byte, offset int64, width int, v int64) {
+	for j := int64(0); j < int64(width); j++ {
+		i, mask := (offset+j)>>3, byte(0x80)>>((offset+j)&7)
+		if uint64(v)&(1<<(int64(width)-1-j)) != 0 {
+			b[i] |= mask
+		} else {
+			b[i] &^= mask
+		}
+	}
+}
+
+// checkBitfieldOverflow adds incr to v, an integer of the type of op, and
+// reports whether the result overflows it. On overflow, the result is
+// wrapped around or saturated, as the OVERFLOW behavior of op says.
+func checkBitfieldOverflow(v, incr int64, op bitfieldOp) (int64, bool) {
+	if !op.signed {
+		uv, maxv := uint64(v), uint64(1)<<op.width-1
+		switch {
+		case uv > maxv || (incr > 0 && uint64(incr) > maxv-uv):
+			if op.overflow == "SAT" {
+				return int64(maxv), true
+			}
+		case incr < 0 && uint64(-incr) > uv:
+			if op.overflow == "SAT" {
+				return 0, true
+			}
+		default:
+			return v + incr, false
+		}
+		return int64((uv + uint64(incr)) & maxv), true
+	}
+
+	maxv := int64(math.MaxInt64)
+	if op.width < 64 {
+		maxv = 1<<(op.width-1) - 1
+	}
+	minv := -maxv - 1
+	switch {
+	// maxv-v and minv-v only overflow for 64 bits integers,
+	// that incr cannot overflow the other way around.
+	case v > maxv || (incr > 0 && (op.width < 64 || v >= 0) && incr > maxv-v):
+		if op.overflow == "SAT" {
+			return maxv, true
+		}
+	case v < minv || (incr < 0 && (op.width < 64 || v < 0) && incr < minv-v):
+		if op.overflow == "SAT" {
+			return minv, true
+		}
+	default:
+		return v + incr, false
+	}
+	w := uint64(v) + uint64(incr)
+	if op.width < 64 {
+		if w&(1<<(op.width-1)) != 0 {
+			w |= math.MaxUint64 << op.width
+		} else {
+			w &^= math.MaxUint64 << op.width
+		}
+	}
+	return int64(w), true
+}
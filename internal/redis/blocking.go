@@ -0,0 +1,170 @@
+package redis
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/Avik32223/redis-server/internal/rediserr"
+)
+
+// errorBlocked is returned by commands that blocked the client, which gets
+// no reply until it is served or times out.
+var errorBlocked = fmt.Errorf("client blocked")
+
+// blockingKey is a key clients are blocked on, in one of the databases.
+type blockingKey struct {
+	db  int
+	key string
+}
+
+// blockedClient is a client blocked by a command until one of its keys
+// holds data. try runs the command again, and replies nil if it would
+// still block.
+type blockedClient struct {
+	c     *Client
+	keys  []blockingKey
+	try   func() (Reply, error)
+	timer *time.Timer
+}
+
+// parseTimeout parses the timeout of a blocking command, in seconds,
+// fractions of seconds allowed. A timeout of 0 blocks forever.
+func parseTimeout(s string, now time.Time) (time.Duration, error) {
+	f, err := parseLongDouble(s)
+	if err != nil {
+		return 0, rediserr.New(rediserr.ERR, "timeout is not a float or out of range")
+	}
+	f.Mul(f, big.NewFloat(1000))
+	if f.IsInf() || f.Cmp(big.NewFloat(math.MaxInt64)) > 0 {
+		return 0, rediserr.New(rediserr.ERR, "timeout is out of range")
+	}
+	ms, acc := f.Int64()
+	if acc == big.Below {
+		ms++
+	}
+	if ms < 0 {
+		return 0, rediserr.New(rediserr.ERR, "timeout is negative")
+	}
+	if ms > math.MaxInt64-now.UnixMilli() {
+		return 0, rediserr.New(rediserr.ERR, "timeout is out of range")
+	}
+	if ms > math.MaxInt64/int64(time.Millisecond) {
+		// Too far away to ever be reached.
+		return 0, nil
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// block runs try, and replies with its reply unless it is nil. The client
+// is blocked on keys otherwise, until try succeeds once one of them holds
+// data, or until timeout, unless it is 0.
+func (c *Client) block(keys []string, timeout time.Duration, try func() (Reply, error)) (Reply, error) {
+	r, err := try()
+	if r != nil || err != nil {
+		return r, err
+	}
+
+	s := c.server
+	b := &blockedClient{c: c, try: try}
+	for _, key := range keys {
+		k := blockingKey{c.dbIndex, key}
+		b.keys = append(b.keys, k)
+		s.blockingKeys[k] = append(s.blockingKeys[k], b)
+	}
+	if timeout > 0 {
+		b.timer = time.AfterFunc(timeout, func() {
+			select {
+			case s.timeoutCh <- b:
+			case <-s.quitCh:
+			}
+		})
+	}
+	c.blocked = b
+	return nil, errorBlocked
+}
+
+// unblock removes b from the queues of the keys it is blocked on.
+func (s *Server) unblock(b *blockedClient) {
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	for _, k := range b.keys {
+		q := s.blockingKeys[k]
+		for i := range q {
+			if q[i] == b {
+				q = append(q[:i], q[i+1:]...)
+				break
+			}
+		}
+		if len(q) == 0 {
+			delete(s.blockingKeys, k)
+		} else {
+			s.blockingKeys[k] = q
+		}
+	}
+	b.c.blocked = nil
+}
+
+// signalKeyAsReady marks key as modified, for the clients blocked on it to
+// be served once the running command is done.
+func (s *Server) signalKeyAsReady(db int, key string) {
+	k := blockingKey{db, key}
+	if len(s.blockingKeys[k]) == 0 || s.readyKeys[k] {
+		return
+	}
+	s.readyKeys[k] = true
+	s.readyQueue = append(s.readyQueue, k)
+}
+
+// serveBlockedClients serves the clients blocked on the keys that are
+// ready, in the order they blocked, for as long as the keys hold data.
+// The requests clients sent while blocked are then run.
+func (s *Server) serveBlockedClients() {
+	var served []*Client
+	for len(s.readyQueue) > 0 {
+		k := s.readyQueue[0]
+		s.readyQueue = s.readyQueue[1:]
+		delete(s.readyKeys, k)
+		for len(s.blockingKeys[k]) > 0 {
+			// Clients block on lists only, and keep waiting
+			// while key holds something else.
+			if l, err := lookupList(s.dbs[k.db], k.key); l == nil || err != nil {
+				break
+			}
+			b := s.blockingKeys[k][0]
+			r, err := b.try()
+			if r == nil && err == nil {
+				break
+			}
+			s.unblock(b)
+			b.c.reply(r, err)
+			served = append(served, b.c)
+		}
+	}
+	for _, c := range served {
+		s.runPending(c)
+	}
+}
+
+// timeoutBlocked replies to a client whose blocking command timed out,
+// unless it was served in the meantime.
+func (s *Server) timeoutBlocked(b *blockedClient) {
+	if b.c.blocked != b {
+		return
+	}
+	s.unblock(b)
+	b.c.reply(NullArrayReply{}, nil)
+	s.runPending(b.c)
+}
+
+// runPending runs the requests a client sent while it was blocked,
+// until it blocks again.
+func (s *Server) runPending(c *Client) {
+	pending := c.pending
+	c.pending = nil
+	for _, m := range pending {
+		s.HandleMessage(m)
+	}
+}
@@ -0,0 +1,137 @@
+package redis
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Avik32223/redis-server/internal/rediserr"
+	"github.com/Avik32223/redis-server/internal/transport"
+)
+
+// version is the redis version this server is compatible with.
+const version = "7.2.0"
+
+type clientFlag uint
+
+const (
+	// clientCloseAfterReply is set when the connection is to be closed
+	// once the pending reply is sent.
+	clientCloseAfterReply clientFlag = 1 << iota
+)
+
+// Client is a connection to the server, and the context every command runs in.
+type Client struct {
+	id      int64
+	name    string
+	addr    string
+	dbIndex int
+	// opts holds the protocol negotiated by the client.
+	opts      SerDeOpts
+	flags     clientFlag
+	createdAt time.Time
+	lastCmd   string
+	// blocked is set while a blocking command waits for data, and pending
+	// holds the requests received in the meantime.
+	blocked *blockedClient
+	pending []transport.Message
+
+	server *Server
+	peer   transport.Peer
+}
+
+func newClient(s *Server, id int64, peer transport.Peer) *Client {
+	return &Client{
+		id:        id,
+		addr:      peer.RemoteAddr().String(),
+		opts:      SerDeOpts{"resp_version": "2"},
+		createdAt: time.Now(),
+		server:    s,
+		peer:      peer,
+	}
+}
+
+// db returns the database selected by the client.
+func (c *Client) db() State {
+	return c.server.dbs[c.dbIndex]
+}
+
+// reply sends the reply of a command, or the error it failed with,
+// in the protocol negotiated by the client.
+func (c *Client) reply(r Reply, err error) error {
+	if c.flags&clientCloseAfterReply != 0 {
+		defer c.peer.Close()
+	}
+	if err != nil {
+		x, _ := Serialize(err, &c.opts)
+		return c.peer.Send([]byte(x))
+	}
+	res, err := Serialize(r, &c.opts)
+	if err != nil {
+		x, _ := Serialize(err, &c.opts)
+		return c.peer.Send([]byte(x))
+	}
+	return c.peer.Send([]byte(res))
+}
+
+// hello switches the protocol of the connection, and replies with the
+// server information in the protocol just negotiated.
+func hello(c *Client, ca ...any) (Reply, error) {
+	proto := c.opts["resp_version"]
+	if len(ca) > 0 {
+		v, err := strconv.Atoi(ca[0].(string))
+		if err != nil {
+			return nil, rediserr.New(rediserr.ERR, "Protocol version is not an integer or out of range")
+		}
+		if v < 2 || v > 3 {
+			return nil, rediserr.NoProto
+		}
+		proto = strconv.Itoa(v)
+	}
+
+	name, setName := "", false
+	for i := 1; i < len(ca); i++ {
+		opt := strings.ToLower(ca[i].(string))
+		switch {
+		case opt == "auth" && i+2 < len(ca):
+			// Only the default user exists, and it requires no password.
+			if ca[i+1].(string) != "default" {
+				return nil, rediserr.WrongPass
+			}
+			i += 2
+		case opt == "setname" && i+1 < len(ca):
+			name, setName = ca[i+1].(string), true
+			if !validConnName(name) {
+				return nil, rediserr.New(rediserr.ERR, "Client names cannot contain spaces, newlines or special characters.")
+			}
+			i++
+		default:
+			return nil, rediserr.Errorf(rediserr.ERR, "Syntax error in HELLO option '%s'", ca[i])
+		}
+	}
+
+	// Nothing changes unless every option is valid.
+	if setName {
+		c.name = name
+	}
+	c.opts["resp_version"] = proto
+	p, _ := strconv.Atoi(proto)
+	return MapReply{
+		{Key: BulkReply("server"), Value: BulkReply("redis")},
+		{Key: BulkReply("version"), Value: BulkReply(version)},
+		{Key: BulkReply("proto"), Value: IntegerReply(p)},
+		{Key: BulkReply("id"), Value: IntegerReply(c.id)},
+		{Key: BulkReply("mode"), Value: BulkReply(standalone)},
+		{Key: BulkReply("role"), Value: BulkReply("master")},
+		{Key: BulkReply("modules"), Value: emptyArray},
+	}, nil
+}
+
+func validConnName(name string) bool {
+	for _, r := range name {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -2,331 +2,313 @@ package redis
 
 import (
 	"fmt"
-	"math"
-	"reflect"
 	"slices"
-	"strconv"
 	"strings"
 	"time"
 
-	"github.com/Avik32223/redis-server/pkg/lists"
+	"github.com/Avik32223/redis-server/internal/rediserr"
 )
 
-var listKind = reflect.TypeOf(lists.List{}).Kind()
 var (
-	errorKeyAbsent      = fmt.Errorf("key absent")
-	errorInvalidCommand = fmt.Errorf("invalid command")
+	errorKeyAbsent = fmt.Errorf("key absent")
 )
 
-type Command func(State, ...any) (any, error)
+// Command runs with the arguments following the command name,
+// on behalf of the client that sent it.
+type Command func(*Client, ...any) (Reply, error)
 
-var commandMap = map[string]Command{
-	"get":     get,
-	"set":     set,
-	"command": command,
-	"ping":    ping,
-	"echo":    echo,
-	"exists":  exists,
-	"del":     del,
-	"incr":    incr,
-	"decr":    decr,
-	"lpush":   lpush,
-	"rpush":   rpush,
+var commands = []*commandSpec{
+	{
+		name: "get", run: get, arity: 2,
+		flags:    []string{flagReadonly, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RO", "ACCESS"},
+		categories: []string{"@read", "@string", "@fast"},
+		group:      "string", since: "1.0.0", complexity: "O(1)",
+		summary: "Returns the string value of a key.",
+	},
+	{
+		name: "set", run: set, arity: -3,
+		flags:    []string{flagWrite, flagDenyOOM},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "UPDATE"},
+		categories: []string{"@write", "@string", "@slow"},
+		group:      "string", since: "1.0.0", complexity: "O(1)",
+		summary: "Sets the string value of a key, ignoring its type. The key is created if it doesn't exist.",
+	},
+	{
+		name: "command", run: command, arity: -1,
+		flags:      []string{flagLoading, flagStale},
+		categories: []string{"@slow", "@connection"},
+		group:      "server", since: "2.8.13", complexity: "O(N) where N is the total number of Redis commands",
+		summary: "Returns detailed information about all commands.",
+	},
+	{
+		name: "ping", run: ping, arity: -1,
+		flags:      []string{flagFast},
+		categories: []string{"@fast", "@connection"},
+		group:      "connection", since: "1.0.0", complexity: "O(1)",
+		summary: "Returns the server's liveliness response.",
+	},
+	{
+		name: "echo", run: echo, arity: 2,
+		flags:      []string{flagFast},
+		categories: []string{"@fast", "@connection"},
+		group:      "connection", since: "1.0.0", complexity: "O(1)",
+		summary: "Returns the given string.",
+	},
+	{
+		name: "hello", run: hello, arity: -1,
+		flags:      []string{flagNoScript, flagLoading, flagStale, flagFast},
+		categories: []string{"@fast", "@connection"},
+		group:      "connection", since: "6.0.0", complexity: "O(1)",
+		summary: "Handshakes with the Redis server.",
+	},
+	{
+		name: "exists", run: exists, arity: -2,
+		flags:    []string{flagReadonly, flagFast},
+		firstKey: 1, lastKey: -1, keyStep: 1, keyFlags: []string{"RO"},
+		categories: []string{"@keyspace", "@read", "@fast"},
+		group:      "generic", since: "1.0.0", complexity: "O(N) where N is the number of keys to check.",
+		summary: "Determines whether one or more keys exist.",
+	},
+	{
+		name: "del", run: del, arity: -2,
+		flags:    []string{flagWrite},
+		firstKey: 1, lastKey: -1, keyStep: 1, keyFlags: []string{"RM", "DELETE"},
+		categories: []string{"@keyspace", "@write", "@slow"},
+		group:      "generic", since: "1.0.0", complexity: "O(N) where N is the number of keys that will be removed.",
+		summary: "Deletes one or more keys.",
+	},
+	{
+		name: "incr", run: incr, arity: 2,
+		flags:    []string{flagWrite, flagDenyOOM, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "UPDATE"},
+		categories: []string{"@write", "@string", "@fast"},
+		group:      "string", since: "1.0.0", complexity: "O(1)",
+		summary: "Increments the integer value of a key by one. Uses 0 as initial value if the key doesn't exist.",
+	},
+	{
+		name: "decr", run: decr, arity: 2,
+		flags:    []string{flagWrite, flagDenyOOM, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "UPDATE"},
+		categories: []string{"@write", "@string", "@fast"},
+		group:      "string", since: "1.0.0", complexity: "O(1)",
+		summary: "Decrements the integer value of a key by one. Uses 0 as initial value if the key doesn't exist.",
+	},
+	{
+		name: "lpush", run: lpush, arity: -3,
+		flags:    []string{flagWrite, flagDenyOOM, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "INSERT"},
+		categories: []string{"@write", "@list", "@fast"},
+		group:      "list", since: "1.0.0", complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments.",
+		summary: "Prepends one or more elements to a list. Creates the key if it doesn't exist.",
+	},
+	{
+		name: "rpush", run: rpush, arity: -3,
+		flags:    []string{flagWrite, flagDenyOOM, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "INSERT"},
+		categories: []string{"@write", "@list", "@fast"},
+		group:      "list", since: "1.0.0", complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments.",
+		summary: "Appends one or more elements to a list. Creates the key if it doesn't exist.",
+	},
 }
 
-func invalidCommand(s State, ca ...any) (any, error) {
-	return nil, errorInvalidCommand
-}
-
-func get(s State, ca ...any) (any, error) {
-	if len(ca) != 1 {
-		return nil, fmt.Errorf("ERR wrong number of arguments for 'get' command")
-	}
-	key := ca[0]
+// lookup returns the value stored at key,
+// deleting it instead if it has expired.
+func lookup(s State, key string) (any, error) {
 	data := *s.Data()
-	switch k := key.(type) {
-	case string:
-		x, ok := data[k]
-		if ok {
-			if time.Now().Before(x.expiresAt) {
-				return x.val, nil
-			}
-			delete(data, k)
+	x, ok := data[key]
+	if ok {
+		if !x.expired(time.Now()) {
+			return x.val, nil
 		}
-		return nil, errorKeyAbsent
+		delete(data, key)
 	}
-	return nil, fmt.Errorf("invalid use. key must be string")
+	return nil, errorKeyAbsent
 }
 
-func set(s State, ca ...any) (any, error) {
-	if len(ca) < 2 {
-		return nil, fmt.Errorf("ERR wrong number of arguments for 'set' command")
-	}
-	now := time.Now()
-	expiryFound := false
-	expiresAt := now.Add(time.Duration(math.MaxInt64))
-	for i := 2; i < len(ca); i++ {
-		switch x := ca[i].(type) {
-		case string:
-			if !expiryFound && i+1 < len(ca) && slices.Index([]string{"EX", "PX", "EXAT", "PXAT"}, x) != -1 {
-				amount, err := strconv.Atoi(ca[i+1].(string))
-				if err != nil {
-					return nil, fmt.Errorf("ERR wrong arguments for 'set' command. '%s' value invalid", x)
-				}
-				if x == "EX" {
-					expiresAt = now.Add(time.Duration(amount) * time.Second)
-				} else if x == "PX" {
-					expiresAt = now.Add(time.Duration(amount) * time.Millisecond)
-				} else if x == "EXAT" {
-					expiresAt = time.Unix(int64(amount), 0)
-				} else if x == "PXAT" {
-					expiresAt = time.UnixMilli(int64(amount))
-				}
-				i++
-			} else {
-				return nil, fmt.Errorf("ERR wrong number of arguments for 'set' command. multiple expiries provided")
-			}
-		}
-	}
-
-	key := ca[0]
-	value := ca[1]
+// storeContainer stores v, a list or a hash modified in place, at key
+// unless it is stored there already, or deletes key instead if v is left
+// empty: redis keys never hold empty containers.
+func storeContainer(s State, key string, v interface{ Len() int }) {
 	data := *s.Data()
-	switch k := key.(type) {
-	case string:
-		data[k] = &stateValue{
-			val:       value,
-			expiresAt: expiresAt,
-		}
-	default:
-		return nil, fmt.Errorf("invalid use. key must be string")
+	if v.Len() == 0 {
+		delete(data, key)
+		return
 	}
-	return "OK", nil
-}
-
-func exists(s State, ca ...any) (any, error) {
-	if len(ca) < 1 {
-		return nil, fmt.Errorf("ERR wrong number of arguments for 'exists' command")
-	}
-	c := 0
-	for _, key := range ca {
-		if _, err := get(s, key); err == nil {
-			c++
-		}
+	if _, ok := data[key]; !ok {
+		data[key] = &stateValue{val: v}
 	}
-	return c, nil
 }
 
-func del(s State, ca ...any) (any, error) {
-	if len(ca) < 1 {
-		return nil, fmt.Errorf("ERR wrong number of arguments for 'del' command")
-	}
-	c := 0
-	data := *s.Data()
-	for _, key := range ca {
-		if _, err := get(s, key); err == nil {
-			delete(data, key.(string))
-			c++
-		}
-	}
-	return c, nil
-}
-
-func incr(s State, ca ...any) (any, error) {
-	if len(ca) < 1 || len(ca) > 2 {
-		return nil, fmt.Errorf("ERR wrong number of arguments for 'incr' command")
-	}
-	v, err := get(s, ca[0])
+func get(c *Client, ca ...any) (Reply, error) {
+	s := c.db()
+	v, err := lookup(s, ca[0].(string))
 	if err != nil {
-		if err != errorKeyAbsent {
-			return v, err
+		if err == errorKeyAbsent {
+			return NullBulkReply{}, nil
 		}
-		v = "0"
+		return nil, err
 	}
-	amount := 1
-	if len(ca) == 2 {
-		switch a := ca[1].(type) {
-		case string:
-			x, err := strconv.Atoi(a)
-			if err != nil {
-				return nil, fmt.Errorf("ERR invalid incr value provided")
-			}
-			amount = x
-		}
-	}
-	switch vt := v.(type) {
-	case string:
-		i, err := strconv.Atoi(vt)
-		if err != nil {
-			return nil, err
-		}
-		nv := i + amount
-		_, err = set(s, ca[0], fmt.Sprint(nv))
-		if err != nil {
-			return nil, err
-		}
-		return nv, nil
+	vt, ok := v.(string)
+	if !ok {
+		return nil, rediserr.WrongType
 	}
-	return nil, fmt.Errorf("ERR wrong number of arguments for 'incr' command")
+	return BulkReply(vt), nil
 }
 
-func decr(s State, ca ...any) (any, error) {
-	if len(ca) < 1 || len(ca) > 2 {
-		return nil, fmt.Errorf("ERR wrong number of arguments for 'decr' command")
-	}
-	v, err := get(s, ca[0])
-	if err != nil {
-		if err != errorKeyAbsent {
-			return v, err
-		}
-		v = "0"
-	}
-	amount := 1
-	if len(ca) == 2 {
-		switch a := ca[1].(type) {
-		case string:
-			x, err := strconv.Atoi(a)
-			if err != nil {
-				return nil, fmt.Errorf("ERR invalid 'decr' value provided")
-			}
-			amount = x
+// set sets key to a string, whatever the type of its current value.
+// With NX or XX, key is only set if it does not exist yet, or if it does.
+// With IFEQ, IFNE, IFDEQ or IFDNE, key is only set if its current value,
+// or the digest of it, matches or differs from the one given.
+// With GET, the value key held is replied, whether it is set or not.
+func set(c *Client, ca ...any) (Reply, error) {
+	s := c.db()
+	key, value := ca[0].(string), ca[1]
+	now := time.Now()
+
+	var get, keepTTL bool
+	var cond valueCondition
+	expiry, expiryValue := "", ""
+	for i := 2; i < len(ca); i++ {
+		opt := strings.ToUpper(ca[i].(string))
+		switch {
+		case (opt == "NX" || opt == "XX") && (cond.op == "" || cond.op == opt):
+			cond.op = opt
+		case isValueCondition(opt) && cond.op == "" && i+1 < len(ca):
+			cond = valueCondition{op: opt, arg: ca[i+1].(string)}
+			i++
+		case opt == "GET":
+			get = true
+		case opt == "KEEPTTL" && expiry == "":
+			keepTTL = true
+			expiry = opt
+		case (opt == "EX" || opt == "PX" || opt == "EXAT" || opt == "PXAT") && expiry == "" && i+1 < len(ca):
+			expiry, expiryValue = opt, ca[i+1].(string)
+			i++
+		default:
+			return nil, rediserr.Syntax
 		}
 	}
-	switch vt := v.(type) {
-	case string:
-		i, err := strconv.Atoi(vt)
-		if err != nil {
-			return nil, err
-		}
-		nv := i - amount
-		_, err = set(s, ca[0], fmt.Sprint(nv))
+
+	var expiresAt time.Time
+	if expiry != "" && !keepTTL {
+		t, err := parseExpiry("set", expiry, expiryValue, now)
 		if err != nil {
 			return nil, err
 		}
-		return nv, nil
+		expiresAt = t
 	}
 
-	return nil, fmt.Errorf("ERR wrong number of arguments for 'decr' command")
-}
+	var old Reply = NullBulkReply{}
+	data := *s.Data()
+	v, err := lookup(s, key)
+	found := err == nil
+	vt, isString := v.(string)
+	if found && !isString && (get || isValueCondition(cond.op)) {
+		return nil, rediserr.WrongType
+	}
+	if found && get {
+		old = BulkReply(vt)
+	}
 
-func lpush(s State, ca ...any) (any, error) {
-	if len(ca) < 2 {
-		return nil, fmt.Errorf("ERR wrong number of arguments for 'lpush' command")
+	if !cond.match(vt, found) {
+		return old, nil
 	}
-	key := ca[0]
-	ca = ca[1:]
-	keyAbsent := false
-	val, err := get(s, key)
-	if err != nil {
-		if err != errorKeyAbsent {
-			return val, err
-		}
-		keyAbsent = true
-		val = *lists.NewList()
+	if keepTTL && found {
+		expiresAt = data[key].expiresAt
 	}
-	vt := reflect.ValueOf(val)
-	switch vt.Kind() {
-	case listKind:
-		pv := vt.Interface().(lists.List)
-		for i := 0; i < len(ca); i++ {
-			pv.Prepend(ca[i])
-		}
-		if keyAbsent {
-			if v, err := set(s, key, pv); err != nil {
-				return v, err
-			}
-		} else {
-			data := *s.Data()
-			nVal := data[key.(string)]
-			nVal.val = pv
-		}
-		return pv.Len(), nil
+	data[key] = &stateValue{
+		val:       value,
+		expiresAt: expiresAt,
 	}
-	return nil, fmt.Errorf("ERR cannot push to a non list value")
+	if get {
+		return old, nil
+	}
+	return okReply, nil
 }
 
-func rpush(s State, ca ...any) (any, error) {
-	if len(ca) < 2 {
-		return nil, fmt.Errorf("ERR wrong number of arguments for 'rpush' command")
-	}
-	key := ca[0]
-	ca = ca[1:]
-	keyAbsent := false
-	val, err := get(s, key)
-	if err != nil {
-		if err != errorKeyAbsent {
-			return val, err
+func exists(c *Client, ca ...any) (Reply, error) {
+	s := c.db()
+	n := 0
+	for _, key := range ca {
+		if _, err := lookup(s, key.(string)); err == nil {
+			n++
 		}
-		keyAbsent = true
-		val = *lists.NewList()
 	}
-	vt := reflect.ValueOf(val)
-	switch vt.Kind() {
-	case listKind:
-		pv := vt.Interface().(lists.List)
-		for i := 0; i < len(ca); i++ {
-			pv.Append(ca[i])
-		}
-		if keyAbsent {
-			if v, err := set(s, key, pv); err != nil {
-				return v, err
-			}
-		} else {
-			data := *s.Data()
-			nVal := data[key.(string)]
-			nVal.val = pv
+	return IntegerReply(n), nil
+}
+
+func del(c *Client, ca ...any) (Reply, error) {
+	s := c.db()
+	n := 0
+	data := *s.Data()
+	for _, key := range ca {
+		if _, err := lookup(s, key.(string)); err == nil {
+			delete(data, key.(string))
+			n++
 		}
-		return pv.Len(), nil
 	}
-	return nil, fmt.Errorf("ERR cannot push to a non list value")
+	return IntegerReply(n), nil
 }
 
-func ping(s State, ca ...any) (any, error) {
-	return "PONG", nil
+func incr(c *Client, ca ...any) (Reply, error) {
+	return incrBy(c.db(), ca[0].(string), 1)
 }
 
-func echo(s State, ca ...any) (any, error) {
-	if len(ca) != 1 {
-		return nil, fmt.Errorf("ERR wrong number of arguments for 'echo' command")
-	}
-	return ca[0], nil
+func decr(c *Client, ca ...any) (Reply, error) {
+	return incrBy(c.db(), ca[0].(string), -1)
 }
 
-func command(s State, ca ...any) (any, error) {
-	return []any{}, nil
+func lpush(c *Client, ca ...any) (Reply, error) {
+	return pushCommand(c, listLeft, ca...)
 }
 
-func newCommand(arr []any) Command {
-	if len(arr) < 1 {
-		return invalidCommand
-	}
+func rpush(c *Client, ca ...any) (Reply, error) {
+	return pushCommand(c, listRight, ca...)
+}
 
-	switch cmdName := arr[0].(type) {
-	case string:
-		cmdName = strings.ToLower(cmdName)
-		cmd, ok := commandMap[cmdName]
-		if !ok {
-			return invalidCommand
-		}
-		return cmd
+// ping replies with PONG, or with its argument if it is given one.
+func ping(c *Client, ca ...any) (Reply, error) {
+	switch len(ca) {
+	case 0:
+		return StatusReply("PONG"), nil
+	case 1:
+		return BulkReply(ca[0].(string)), nil
 	}
+	return nil, rediserr.WrongArity("ping")
+}
 
-	return get
+func echo(c *Client, ca ...any) (Reply, error) {
+	return BulkReply(ca[0].(string)), nil
 }
 
-func RunCommand(s State, b []byte) (any, error) {
-	sa := string(b)
-	arr := make([]any, 0)
-	if c := eatArray(b, 0); c != 0 {
-		arr, _ = parseArray(sa)
-	} else {
-		for _, s := range strings.Split(sa, " ") {
-			arr = append(arr, s)
-		}
+func RunCommand(c *Client, b []byte) (Reply, error) {
+	args, n, _, err := parseRequest(b)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 || len(args) < 1 {
+		return nil, rediserr.UnknownCommand("", nil)
 	}
-	if len(arr) < 1 {
-		return nil, errorInvalidCommand
+	arr := make([]any, len(args))
+	strs := make([]string, len(args))
+	for i, a := range args {
+		strs[i] = string(a)
+		arr[i] = strs[i]
+	}
+	cmd, ok := lookupCommand(strs[0])
+	if !ok {
+		return nil, rediserr.UnknownCommand(strs[0], strs[1:])
+	}
+	if !cmd.checkArity(len(arr)) {
+		return nil, rediserr.WrongArity(cmd.name)
+	}
+	c.lastCmd = cmd.name
+	r, err := cmd.run(c, arr[1:]...)
+	if err == nil && slices.Contains(cmd.flags, flagWrite) {
+		for _, i := range cmd.keys(arr) {
+			c.server.signalKeyAsReady(c.dbIndex, strs[i])
+		}
 	}
-	cmd := newCommand(arr)
-	return cmd(s, arr[1:]...)
+	return r, err
 }
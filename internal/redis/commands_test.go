@@ -0,0 +1,995 @@
+package redis
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Avik32223/redis-server/internal/transport"
+)
+
+type testPeer struct {
+	sent   strings.Builder
+	closed bool
+}
+
+func (p *testPeer) Send(b []byte) error {
+	p.sent.Write(b)
+	return nil
+}
+
+func (p *testPeer) Close() error {
+	p.closed = true
+	return nil
+}
+
+func (p *testPeer) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 50000}
+}
+
+// request encodes args the way redis clients send them.
+func request(args ...string) []byte {
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	return []byte(b.String())
+}
+
+type commandTest struct {
+	args []string
+	want string
+}
+
+// runCommands sends every command in order on a single connection,
+// and checks the raw reply to each of them.
+func runCommands(t *testing.T, s *Server, tests []commandTest) {
+	t.Helper()
+	peer := new(testPeer)
+	for _, tt := range tests {
+		peer.sent.Reset()
+		s.HandleMessage(transport.Message{Peer: peer, Payload: request(tt.args...)})
+		if got := peer.sent.String(); got != tt.want {
+			t.Errorf("%q = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func Test_hello(t *testing.T) {
+	info := func(proto string) string {
+		return "$6\r\nserver\r\n$5\r\nredis\r\n$7\r\nversion\r\n$5\r\n7.2.0\r\n$5\r\nproto\r\n:" + proto + "\r\n" +
+			"$2\r\nid\r\n:1\r\n$4\r\nmode\r\n$10\r\nstandalone\r\n$4\r\nrole\r\n$6\r\nmaster\r\n$7\r\nmodules\r\n*0\r\n"
+	}
+	s := NewServer(":0")
+	runCommands(t, s, []commandTest{
+		{[]string{"HELLO"}, "*14\r\n" + info("2")},
+		{[]string{"HELLO", "4"}, "-NOPROTO unsupported protocol version\r\n"},
+		{[]string{"HELLO", "three"}, "-ERR Protocol version is not an integer or out of range\r\n"},
+		{[]string{"HELLO", "3", "SETNAME", "a b"}, "-ERR Client names cannot contain spaces, newlines or special characters.\r\n"},
+		{[]string{"HELLO", "3", "AUTH", "admin", "secret"}, "-WRONGPASS invalid username-password pair or user is disabled.\r\n"},
+		{[]string{"HELLO", "3", "SETNAME"}, "-ERR Syntax error in HELLO option 'SETNAME'\r\n"},
+		// Failed attempts leave the protocol unchanged.
+		{[]string{"ECHO", "hi"}, "$2\r\nhi\r\n"},
+		{[]string{"HELLO", "3", "AUTH", "default", "secret", "SETNAME", "worker"}, "%7\r\n" + info("3")},
+		{[]string{"HELLO", "2"}, "*14\r\n" + info("2")},
+	})
+	for _, c := range s.clients {
+		if c.name != "worker" {
+			t.Errorf("client name = %q, want %q", c.name, "worker")
+		}
+	}
+}
+
+func Test_command(t *testing.T) {
+	getInfo := "*10\r\n$3\r\nget\r\n:2\r\n*2\r\n+readonly\r\n+fast\r\n:1\r\n:1\r\n:1\r\n*3\r\n+@read\r\n+@string\r\n+@fast\r\n*0\r\n" +
+		"*1\r\n*6\r\n$5\r\nflags\r\n*2\r\n+RO\r\n+ACCESS\r\n" +
+		"$12\r\nbegin_search\r\n*4\r\n$4\r\ntype\r\n$5\r\nindex\r\n$4\r\nspec\r\n*2\r\n$5\r\nindex\r\n:1\r\n" +
+		"$9\r\nfind_keys\r\n*4\r\n$4\r\ntype\r\n$5\r\nrange\r\n$4\r\nspec\r\n*6\r\n$7\r\nlastkey\r\n:0\r\n$7\r\nkeystep\r\n:1\r\n$5\r\nlimit\r\n:0\r\n" +
+		"*0\r\n"
+	runCommands(t, NewServer(":0"), []commandTest{
+		{[]string{"COMMAND", "COUNT"}, fmt.Sprintf(":%d\r\n", len(commandTable))},
+		{[]string{"COMMAND", "INFO", "GET", "nosuch"}, "*2\r\n" + getInfo + "*-1\r\n"},
+		{[]string{"COMMAND", "DOCS", "echo", "nosuch"}, "*2\r\n$4\r\necho\r\n*8\r\n$7\r\nsummary\r\n$25\r\nReturns the given string.\r\n" +
+			"$5\r\nsince\r\n$5\r\n1.0.0\r\n$5\r\ngroup\r\n$10\r\nconnection\r\n$10\r\ncomplexity\r\n$4\r\nO(1)\r\n"},
+		{[]string{"COMMAND", "DOCS", "bigget"}, "*2\r\n$6\r\nbigget\r\n*6\r\n$7\r\nsummary\r\n$74\r\nReturns the arbitrary precision integer value of a key. Not part of Redis.\r\n" +
+			"$5\r\ngroup\r\n$6\r\nbignum\r\n$10\r\ncomplexity\r\n$50\r\nO(N) where N is the number of digits of the value.\r\n"},
+		{[]string{"COMMAND", "LIST", "FILTERBY", "ACLCAT", "bitmap"}, "*7\r\n$8\r\nbitcount\r\n$8\r\nbitfield\r\n$11\r\nbitfield_ro\r\n" +
+			"$5\r\nbitop\r\n$6\r\nbitpos\r\n$6\r\ngetbit\r\n$6\r\nsetbit\r\n"},
+		{[]string{"COMMAND", "LIST", "FILTERBY", "PATTERN", "*CR"}, "*4\r\n$7\r\nbigdecr\r\n$7\r\nbigincr\r\n$4\r\ndecr\r\n$4\r\nincr\r\n"},
+		{[]string{"COMMAND", "LIST", "FILTERBY", "MODULE", "json"}, "*0\r\n"},
+		{[]string{"COMMAND", "LIST", "FILTERBY"}, "-ERR syntax error\r\n"},
+		{[]string{"COMMAND", "GETKEYS", "SET", "a", "b", "EX", "10"}, "*1\r\n$1\r\na\r\n"},
+		{[]string{"COMMAND", "GETKEYS", "DEL", "a", "b", "c"}, "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+		{[]string{"COMMAND", "GETKEYS", "PING"}, "-ERR The command has no key arguments\r\n"},
+		{[]string{"COMMAND", "GETKEYS", "GET"}, "-ERR Invalid number of arguments specified for command\r\n"},
+		{[]string{"COMMAND", "GETKEYS", "nosuch"}, "-ERR Invalid command specified\r\n"},
+		{[]string{"COMMAND", "GETKEYS"}, "-ERR wrong number of arguments for 'command|getkeys' command\r\n"},
+		{[]string{"COMMAND", "FOO"}, "-ERR unknown subcommand 'FOO'. Try COMMAND HELP.\r\n"},
+		{[]string{"GET"}, "-ERR wrong number of arguments for 'get' command\r\n"},
+		{[]string{"GET", "a", "b"}, "-ERR wrong number of arguments for 'get' command\r\n"},
+		{[]string{"SET", "a"}, "-ERR wrong number of arguments for 'set' command\r\n"},
+	})
+}
+
+func TestServer_client(t *testing.T) {
+	s := NewServer(":0")
+	p1, p2 := new(testPeer), new(testPeer)
+	s.HandleMessage(transport.Message{Peer: p1, Payload: request("PING")})
+	s.HandleMessage(transport.Message{Peer: p2, Payload: request("ECHO", "hi")})
+	s.HandleMessage(transport.Message{Peer: p1, Payload: request("GET", "a")})
+
+	c1, c2 := s.clients[p1], s.clients[p2]
+	if c1.id != 1 || c2.id != 2 {
+		t.Errorf("client ids = %d, %d, want 1, 2", c1.id, c2.id)
+	}
+	if c1.lastCmd != "get" || c2.lastCmd != "echo" {
+		t.Errorf("client last commands = %q, %q, want %q, %q", c1.lastCmd, c2.lastCmd, "get", "echo")
+	}
+	if c1.addr != "127.0.0.1:50000" || c1.dbIndex != 0 {
+		t.Errorf("client addr, db = %q, %d, want %q, %d", c1.addr, c1.dbIndex, "127.0.0.1:50000", 0)
+	}
+
+	s.HandleMessage(transport.Message{Peer: p1, Err: io.EOF})
+	if _, ok := s.clients[p1]; ok || !p1.closed {
+		t.Errorf("client of a disconnected peer is kept")
+	}
+	s.HandleMessage(transport.Message{Peer: p2, Err: protocolError("invalid bulk length")})
+	if got := p2.sent.String(); !strings.HasSuffix(got, "-ERR Protocol error: invalid bulk length\r\n") || !p2.closed {
+		t.Errorf("protocol error reply = %q, closed = %v", got, p2.closed)
+	}
+}
+
+func Test_errors(t *testing.T) {
+	runCommands(t, NewServer(":0"), []commandTest{
+		{[]string{"FOO", "a", "b c"}, "-ERR unknown command 'FOO', with args beginning with: 'a' 'b c' \r\n"},
+		{[]string{"FOO"}, "-ERR unknown command 'FOO', with args beginning with: \r\n"},
+		{[]string{"FOO", strings.Repeat("x", 200), "y"}, "-ERR unknown command 'FOO', with args beginning with: '" + strings.Repeat("x", 128) + "' \r\n"},
+		{[]string{"RPUSH", "list", "a"}, ":1\r\n"},
+		{[]string{"GET", "list"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"INCR", "list"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"SET", "str", "a"}, "+OK\r\n"},
+		{[]string{"LPUSH", "str", "a"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"INCR", "str"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"SET", "str", "a", "EX", "ten"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"SET", "str", "a", "FOO"}, "-ERR syntax error\r\n"},
+	})
+}
+
+func Test_ping(t *testing.T) {
+	runCommands(t, NewServer(":0"), []commandTest{
+		{[]string{"PING"}, "+PONG\r\n"},
+		{[]string{"PING", "hi"}, "$2\r\nhi\r\n"},
+		{[]string{"PING", ""}, "$0\r\n\r\n"},
+		{[]string{"PING", "a", "b"}, "-ERR wrong number of arguments for 'ping' command\r\n"},
+	})
+}
+
+func Test_set(t *testing.T) {
+	s := NewServer(":0")
+	runCommands(t, s, []commandTest{
+		{[]string{"SET", "lock", "a", "NX", "PX", "30000"}, "+OK\r\n"},
+		{[]string{"SET", "lock", "b", "NX", "PX", "30000"}, "$-1\r\n"},
+		{[]string{"SET", "missing", "b", "XX"}, "$-1\r\n"},
+		{[]string{"EXISTS", "missing"}, ":0\r\n"},
+		{[]string{"SET", "lock", "b", "xx", "GET"}, "$1\r\na\r\n"},
+		{[]string{"SET", "lock", "c", "NX", "GET"}, "$1\r\nb\r\n"},
+		{[]string{"SET", "new", "c", "GET"}, "$-1\r\n"},
+		{[]string{"SET", "lock", "c", "NX", "XX"}, "-ERR syntax error\r\n"},
+		{[]string{"SET", "lock", "c", "EX", "10", "PX", "10"}, "-ERR syntax error\r\n"},
+		{[]string{"SET", "lock", "c", "EX", "10", "KEEPTTL"}, "-ERR syntax error\r\n"},
+		{[]string{"SET", "lock", "c", "EX"}, "-ERR syntax error\r\n"},
+		{[]string{"SET", "lock", "c", "EX", "ten", "FOO"}, "-ERR syntax error\r\n"},
+		{[]string{"SET", "lock", "c", "EX", "ten"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"SET", "lock", "c", "EX", "0"}, "-ERR invalid expire time in 'set' command\r\n"},
+		{[]string{"SET", "lock", "c", "PX", "-5"}, "-ERR invalid expire time in 'set' command\r\n"},
+		{[]string{"SET", "lock", "c", "EX", "9223372036854775"}, "-ERR invalid expire time in 'set' command\r\n"},
+		{[]string{"SET", "lock", "c", "PX", "9223372036854775807"}, "-ERR invalid expire time in 'set' command\r\n"},
+		{[]string{"SET", "lock", "c", "EX", "9223372036854775808"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"SET", "lock", "c", "PX", "30000"}, "+OK\r\n"},
+		{[]string{"SET", "lock", "d", "KEEPTTL"}, "+OK\r\n"},
+		{[]string{"RPUSH", "list", "a"}, ":1\r\n"},
+		{[]string{"SET", "list", "c", "GET"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"SET", "list", "c"}, "+OK\r\n"},
+		{[]string{"SET", "past", "c", "PXAT", "1"}, "+OK\r\n"},
+		{[]string{"EXISTS", "past"}, ":0\r\n"},
+	})
+
+	data := *s.dbs[0].Data()
+	if ttl := time.Until(data["lock"].expiresAt); ttl < 29*time.Second || ttl > 30*time.Second {
+		t.Errorf("SET KEEPTTL changed the expiry, ttl = %v", ttl)
+	}
+	if !data["new"].expiresAt.IsZero() {
+		t.Errorf("SET without expiry set an expiry: %v", data["new"].expiresAt)
+	}
+}
+
+func Test_stringRanges(t *testing.T) {
+	s := NewServer(":0")
+	runCommands(t, s, []commandTest{
+		{[]string{"APPEND", "log", "a\r\n"}, ":3\r\n"},
+		{[]string{"APPEND", "log", "b\x00"}, ":5\r\n"},
+		{[]string{"GET", "log"}, "$5\r\na\r\nb\x00\r\n"},
+		{[]string{"STRLEN", "log"}, ":5\r\n"},
+		{[]string{"STRLEN", "missing"}, ":0\r\n"},
+		{[]string{"SET", "s", "This is a string"}, "+OK\r\n"},
+		{[]string{"GETRANGE", "s", "0", "3"}, "$4\r\nThis\r\n"},
+		{[]string{"GETRANGE", "s", "-3", "-1"}, "$3\r\ning\r\n"},
+		{[]string{"GETRANGE", "s", "0", "-1"}, "$16\r\nThis is a string\r\n"},
+		{[]string{"GETRANGE", "s", "10", "100"}, "$6\r\nstring\r\n"},
+		{[]string{"GETRANGE", "s", "-1", "-5"}, "$0\r\n\r\n"},
+		{[]string{"GETRANGE", "s", "-100", "2"}, "$3\r\nThi\r\n"},
+		{[]string{"GETRANGE", "s", "5", "3"}, "$0\r\n\r\n"},
+		{[]string{"GETRANGE", "missing", "0", "-1"}, "$0\r\n\r\n"},
+		{[]string{"GETRANGE", "s", "a", "1"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"SUBSTR", "s", "-6", "-1"}, "$6\r\nstring\r\n"},
+		{[]string{"SETRANGE", "s", "10", "Redis!"}, ":16\r\n"},
+		{[]string{"GET", "s"}, "$16\r\nThis is a Redis!\r\n"},
+		{[]string{"SETRANGE", "pad", "3", "ab"}, ":5\r\n"},
+		{[]string{"GET", "pad"}, "$5\r\n\x00\x00\x00ab\r\n"},
+		{[]string{"SETRANGE", "empty", "3", ""}, ":0\r\n"},
+		{[]string{"EXISTS", "empty"}, ":0\r\n"},
+		{[]string{"SETRANGE", "s", "-1", "x"}, "-ERR offset is out of range\r\n"},
+		{[]string{"SETRANGE", "s", "536870911", "xy"}, "-ERR string exceeds maximum allowed size (proto-max-bulk-len)\r\n"},
+		{[]string{"RPUSH", "list", "a"}, ":1\r\n"},
+		{[]string{"APPEND", "list", "a"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"STRLEN", "list"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"GETRANGE", "list", "0", "1"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"SETRANGE", "list", "0", "a"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"SET", "ttl", "a", "EX", "100"}, "+OK\r\n"},
+		{[]string{"APPEND", "ttl", "b"}, ":2\r\n"},
+		{[]string{"SETRANGE", "ttl", "0", "c"}, ":2\r\n"},
+	})
+	if (*s.dbs[0].Data())["ttl"].expiresAt.IsZero() {
+		t.Errorf("APPEND and SETRANGE cleared the expiry of the key")
+	}
+}
+
+func Test_multiKeyStrings(t *testing.T) {
+	runCommands(t, NewServer(":0"), []commandTest{
+		{[]string{"MSET", "a", "1", "b", "2", "a", "3"}, "+OK\r\n"},
+		{[]string{"RPUSH", "list", "x"}, ":1\r\n"},
+		{[]string{"MGET", "a", "missing", "b", "list"}, "*4\r\n$1\r\n3\r\n$-1\r\n$1\r\n2\r\n$-1\r\n"},
+		{[]string{"MSET", "a", "1", "b"}, "-ERR wrong number of arguments for 'mset' command\r\n"},
+		{[]string{"MSETNX", "c", "1", "list", "2"}, ":0\r\n"},
+		{[]string{"EXISTS", "c"}, ":0\r\n"},
+		{[]string{"MSETNX", "c", "1", "d", "2"}, ":1\r\n"},
+		{[]string{"MGET", "c", "d"}, "*2\r\n$1\r\n1\r\n$1\r\n2\r\n"},
+		{[]string{"MSETNX", "e"}, "-ERR wrong number of arguments for 'msetnx' command\r\n"},
+		{[]string{"COMMAND", "GETKEYS", "MSET", "a", "1", "b", "2"}, "*2\r\n$1\r\na\r\n$1\r\nb\r\n"},
+	})
+}
+
+func Test_getModify(t *testing.T) {
+	s := NewServer(":0")
+	data := *s.dbs[0].Data()
+	runCommands(t, s, []commandTest{
+		{[]string{"SET", "session", "alice"}, "+OK\r\n"},
+		{[]string{"GETEX", "session", "EX", "100"}, "$5\r\nalice\r\n"},
+	})
+	if ttl := time.Until(data["session"].expiresAt); ttl < 99*time.Second || ttl > 100*time.Second {
+		t.Errorf("GETEX EX 100 set a ttl of %v", ttl)
+	}
+	runCommands(t, s, []commandTest{
+		{[]string{"GETEX", "session"}, "$5\r\nalice\r\n"},
+		{[]string{"GETEX", "session", "PERSIST"}, "$5\r\nalice\r\n"},
+	})
+	if !data["session"].expiresAt.IsZero() {
+		t.Errorf("GETEX PERSIST kept the expiry %v", data["session"].expiresAt)
+	}
+	runCommands(t, s, []commandTest{
+		{[]string{"GETEX", "session", "EX", "10", "PX", "10"}, "-ERR syntax error\r\n"},
+		{[]string{"GETEX", "session", "PERSIST", "EX", "10"}, "-ERR syntax error\r\n"},
+		{[]string{"GETEX", "session", "EX"}, "-ERR syntax error\r\n"},
+		{[]string{"GETEX", "session", "KEEPTTL"}, "-ERR syntax error\r\n"},
+		{[]string{"GETEX", "session", "EX", "0"}, "-ERR invalid expire time in 'getex' command\r\n"},
+		{[]string{"GETEX", "missing", "EX", "0"}, "-ERR invalid expire time in 'getex' command\r\n"},
+		{[]string{"GETEX", "missing", "PX", "-1"}, "-ERR invalid expire time in 'getex' command\r\n"},
+		{[]string{"GETEX", "missing", "EX", "abc"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"GETEX", "missing", "EX", "abc", "PX", "1"}, "-ERR syntax error\r\n"},
+		{[]string{"GETEX", "missing", "EX", "10"}, "$-1\r\n"},
+		{[]string{"GETEX", "session", "PXAT", "1"}, "$5\r\nalice\r\n"},
+		{[]string{"EXISTS", "session"}, ":0\r\n"},
+		{[]string{"SET", "token", "t1", "EX", "100"}, "+OK\r\n"},
+		{[]string{"GETDEL", "token"}, "$2\r\nt1\r\n"},
+		{[]string{"GETDEL", "token"}, "$-1\r\n"},
+		{[]string{"GETSET", "token", "t2"}, "$-1\r\n"},
+		{[]string{"GETSET", "token", "t3"}, "$2\r\nt2\r\n"},
+		{[]string{"RPUSH", "list", "a"}, ":1\r\n"},
+		{[]string{"GETEX", "list"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"GETDEL", "list"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"GETSET", "list", "a"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+	})
+}
+
+func Test_incr(t *testing.T) {
+	s := NewServer(":0")
+	runCommands(t, s, []commandTest{
+		{[]string{"INCR", "n"}, ":1\r\n"},
+		{[]string{"INCRBY", "n", "41"}, ":42\r\n"},
+		{[]string{"DECR", "n"}, ":41\r\n"},
+		{[]string{"DECRBY", "n", "-9"}, ":50\r\n"},
+		{[]string{"INCR", "n", "5"}, "-ERR wrong number of arguments for 'incr' command\r\n"},
+		{[]string{"INCRBY", "n", "1.5"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"INCRBY", "n", "+1"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"SET", "max", "9223372036854775806"}, "+OK\r\n"},
+		{[]string{"INCR", "max"}, ":9223372036854775807\r\n"},
+		{[]string{"INCR", "max"}, "-ERR increment or decrement would overflow\r\n"},
+		{[]string{"SET", "min", "-9223372036854775808"}, "+OK\r\n"},
+		{[]string{"DECR", "min"}, "-ERR increment or decrement would overflow\r\n"},
+		{[]string{"DECRBY", "min", "-9223372036854775808"}, "-ERR decrement would overflow\r\n"},
+		{[]string{"SET", "big", "9223372036854775808"}, "+OK\r\n"},
+		{[]string{"INCR", "big"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"SET", "spaced", " 1"}, "+OK\r\n"},
+		{[]string{"INCR", "spaced"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"SET", "f", "10.50", "EX", "100"}, "+OK\r\n"},
+		{[]string{"INCRBYFLOAT", "f", "0.1"}, "$4\r\n10.6\r\n"},
+		{[]string{"INCRBYFLOAT", "f", "-5"}, "$3\r\n5.6\r\n"},
+		{[]string{"SET", "e", "5.0e3"}, "+OK\r\n"},
+		{[]string{"INCRBYFLOAT", "e", "2.0e2"}, "$4\r\n5200\r\n"},
+		{[]string{"INCRBYFLOAT", "new", "-0.0"}, "$1\r\n0\r\n"},
+		{[]string{"INCRBYFLOAT", "new", "3"}, "$1\r\n3\r\n"},
+		{[]string{"INCRBYFLOAT", "new", "abc"}, "-ERR value is not a valid float\r\n"},
+		{[]string{"INCRBYFLOAT", "new", " 1"}, "-ERR value is not a valid float\r\n"},
+		{[]string{"INCRBYFLOAT", "new", "1e5000"}, "-ERR value is not a valid float\r\n"},
+		{[]string{"INCRBYFLOAT", "new", "inf"}, "-ERR increment would produce NaN or Infinity\r\n"},
+		{[]string{"INCRBYFLOAT", "spaced", "1"}, "-ERR value is not a valid float\r\n"},
+		{[]string{"INCR", "f"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"SET", "ttl", "1", "EX", "100"}, "+OK\r\n"},
+		{[]string{"INCRBY", "ttl", "2"}, ":3\r\n"},
+	})
+	data := *s.dbs[0].Data()
+	for _, key := range []string{"f", "ttl"} {
+		if data[key].expiresAt.IsZero() {
+			t.Errorf("incrementing %q cleared its expiry", key)
+		}
+	}
+}
+
+func Test_compareAndSet(t *testing.T) {
+	s := NewServer(":0")
+	data := *s.dbs[0].Data()
+	d := stringDigest("owner-1")
+	runCommands(t, s, []commandTest{
+		{[]string{"SET", "lock", "owner-1", "EX", "100"}, "+OK\r\n"},
+		{[]string{"SET", "lock", "owner-2", "IFEQ", "owner-3"}, "$-1\r\n"},
+		{[]string{"SET", "lock", "owner-2", "IFNE", "owner-1", "GET"}, "$7\r\nowner-1\r\n"},
+		{[]string{"DIGEST", "lock"}, "$16\r\n" + d + "\r\n"},
+		{[]string{"DIGEST", "missing"}, "$-1\r\n"},
+		{[]string{"SET", "lock", "owner-2", "IFDEQ", strings.ToUpper(d), "KEEPTTL"}, "+OK\r\n"},
+		{[]string{"GET", "lock"}, "$7\r\nowner-2\r\n"},
+	})
+	if data["lock"].expiresAt.IsZero() {
+		t.Errorf("SET IFDEQ KEEPTTL dropped the expiry")
+	}
+	runCommands(t, s, []commandTest{
+		{[]string{"SET", "lock", "owner-3", "IFDNE", stringDigest("owner-2")}, "$-1\r\n"},
+		{[]string{"SET", "lock", "owner-3", "IFEQ", "owner-2"}, "+OK\r\n"},
+		{[]string{"SET", "missing", "v", "IFEQ", "v"}, "$-1\r\n"},
+		{[]string{"SET", "created", "v", "IFNE", "v"}, "+OK\r\n"},
+		{[]string{"SET", "lock", "v", "IFEQ", "a", "NX"}, "-ERR syntax error\r\n"},
+		{[]string{"SET", "lock", "v", "NX", "IFEQ", "a"}, "-ERR syntax error\r\n"},
+		{[]string{"SET", "lock", "v", "IFEQ"}, "-ERR syntax error\r\n"},
+		{[]string{"DELEX", "lock", "IFEQ", "owner-1"}, ":0\r\n"},
+		{[]string{"DELEX", "lock", "IFDEQ", stringDigest("owner-3")}, ":1\r\n"},
+		{[]string{"DELEX", "lock", "IFEQ", "owner-3"}, ":0\r\n"},
+		{[]string{"DELEX", "created", "IFEQ"}, "-ERR syntax error\r\n"},
+		{[]string{"DELEX", "created", "IF", "v"}, "-ERR syntax error\r\n"},
+		{[]string{"DELEX", "created"}, ":1\r\n"},
+		{[]string{"RPUSH", "queue", "a"}, ":1\r\n"},
+		{[]string{"SET", "queue", "v", "IFNE", "a"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"DELEX", "queue", "IFNE", "a"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"DIGEST", "queue"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"DELEX", "queue"}, ":1\r\n"},
+		// The digest redis documents for this value.
+		{[]string{"SET", "greeting", "Hello world"}, "+OK\r\n"},
+		{[]string{"DIGEST", "greeting"}, "$16\r\nb6acb9d84a38ff74\r\n"},
+		{[]string{"DELEX", "greeting", "IFDEQ", "b6acb9d84a38ff74"}, ":1\r\n"},
+	})
+}
+
+func Test_lcs(t *testing.T) {
+	s := NewServer(":0")
+	runCommands(t, s, []commandTest{
+		{[]string{"MSET", "key1", "ohmytext", "key2", "mynewtext"}, "+OK\r\n"},
+		{[]string{"LCS", "key1", "key2"}, "$6\r\nmytext\r\n"},
+		{[]string{"LCS", "key1", "key2", "LEN"}, ":6\r\n"},
+		{[]string{"LCS", "key1", "key2", "IDX"}, "*4\r\n$7\r\nmatches\r\n*2\r\n" +
+			"*2\r\n*2\r\n:4\r\n:7\r\n*2\r\n:5\r\n:8\r\n" +
+			"*2\r\n*2\r\n:2\r\n:3\r\n*2\r\n:0\r\n:1\r\n" +
+			"$3\r\nlen\r\n:6\r\n"},
+		{[]string{"LCS", "key1", "key2", "IDX", "MINMATCHLEN", "4", "WITHMATCHLEN"}, "*4\r\n$7\r\nmatches\r\n*1\r\n" +
+			"*3\r\n*2\r\n:4\r\n:7\r\n*2\r\n:5\r\n:8\r\n:4\r\n" +
+			"$3\r\nlen\r\n:6\r\n"},
+		{[]string{"LCS", "key1", "missing"}, "$0\r\n\r\n"},
+		{[]string{"LCS", "key1", "key2", "IDX", "LEN"}, "-ERR If you want both the length and indexes, please just use IDX.\r\n"},
+		{[]string{"LCS", "key1", "key2", "MINMATCHLEN"}, "-ERR syntax error\r\n"},
+		{[]string{"LCS", "key1", "key2", "MINMATCHLEN", "x"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"RPUSH", "list", "a"}, ":1\r\n"},
+		{[]string{"LCS", "key1", "list"}, "-ERR The specified keys must contain string values\r\n"},
+		{[]string{"HELLO", "3"}, "%7\r\n$6\r\nserver\r\n$5\r\nredis\r\n$7\r\nversion\r\n$5\r\n7.2.0\r\n$5\r\nproto\r\n:3\r\n" +
+			"$2\r\nid\r\n:1\r\n$4\r\nmode\r\n$10\r\nstandalone\r\n$4\r\nrole\r\n$6\r\nmaster\r\n$7\r\nmodules\r\n*0\r\n"},
+		{[]string{"LCS", "key1", "key2", "IDX", "MINMATCHLEN", "3"}, "%2\r\n$7\r\nmatches\r\n*1\r\n" +
+			"*2\r\n*2\r\n:4\r\n:7\r\n*2\r\n:5\r\n:8\r\n" +
+			"$3\r\nlen\r\n:6\r\n"},
+	})
+}
+
+func Test_bitmaps(t *testing.T) {
+	runCommands(t, NewServer(":0"), []commandTest{
+		{[]string{"SETBIT", "active", "7", "1"}, ":0\r\n"},
+		{[]string{"SETBIT", "active", "7", "1"}, ":1\r\n"},
+		{[]string{"GET", "active"}, "$1\r\n\x01\r\n"},
+		{[]string{"SETBIT", "active", "17", "1"}, ":0\r\n"},
+		{[]string{"STRLEN", "active"}, ":3\r\n"},
+		{[]string{"GETBIT", "active", "17"}, ":1\r\n"},
+		{[]string{"GETBIT", "active", "16"}, ":0\r\n"},
+		{[]string{"GETBIT", "active", "1000"}, ":0\r\n"},
+		{[]string{"GETBIT", "missing", "0"}, ":0\r\n"},
+		{[]string{"SETBIT", "active", "-1", "1"}, "-ERR bit offset is not an integer or out of range\r\n"},
+		{[]string{"SETBIT", "active", "4294967296", "1"}, "-ERR bit offset is not an integer or out of range\r\n"},
+		{[]string{"SETBIT", "active", "0", "2"}, "-ERR bit is not an integer or out of range\r\n"},
+
+		{[]string{"SET", "mykey", "foobar"}, "+OK\r\n"},
+		{[]string{"BITCOUNT", "mykey"}, ":26\r\n"},
+		{[]string{"BITCOUNT", "mykey", "0", "0"}, ":4\r\n"},
+		{[]string{"BITCOUNT", "mykey", "1", "1"}, ":6\r\n"},
+		{[]string{"BITCOUNT", "mykey", "1", "1", "BYTE"}, ":6\r\n"},
+		{[]string{"BITCOUNT", "mykey", "5", "30", "BIT"}, ":17\r\n"},
+		{[]string{"BITCOUNT", "mykey", "-2", "-1"}, ":7\r\n"},
+		{[]string{"BITCOUNT", "mykey", "-1", "-2"}, ":0\r\n"},
+		{[]string{"BITCOUNT", "mykey", "0"}, "-ERR syntax error\r\n"},
+		{[]string{"BITCOUNT", "mykey", "0", "1", "BITS"}, "-ERR syntax error\r\n"},
+		{[]string{"BITCOUNT", "missing", "0", "1"}, ":0\r\n"},
+
+		{[]string{"SET", "mykey", "\xff\xf0\x00"}, "+OK\r\n"},
+		{[]string{"BITPOS", "mykey", "0"}, ":12\r\n"},
+		{[]string{"SET", "mykey", "\x00\xff\xf0"}, "+OK\r\n"},
+		{[]string{"BITPOS", "mykey", "1", "0"}, ":8\r\n"},
+		{[]string{"BITPOS", "mykey", "1", "2"}, ":16\r\n"},
+		{[]string{"BITPOS", "mykey", "1", "2", "-1", "BYTE"}, ":16\r\n"},
+		{[]string{"BITPOS", "mykey", "1", "7", "15", "BIT"}, ":8\r\n"},
+		{[]string{"BITPOS", "mykey", "1", "7", "-3", "BIT"}, ":8\r\n"},
+		{[]string{"SET", "mykey", "\xff\xff\xff"}, "+OK\r\n"},
+		{[]string{"BITPOS", "mykey", "0"}, ":24\r\n"},
+		{[]string{"BITPOS", "mykey", "0", "1"}, ":24\r\n"},
+		{[]string{"BITPOS", "mykey", "0", "1", "2"}, ":-1\r\n"},
+		{[]string{"BITPOS", "mykey", "0", "3"}, ":-1\r\n"},
+		{[]string{"BITPOS", "missing", "0"}, ":0\r\n"},
+		{[]string{"BITPOS", "missing", "1"}, ":-1\r\n"},
+		{[]string{"BITPOS", "mykey", "2"}, "-ERR The bit argument must be 1 or 0.\r\n"},
+
+		{[]string{"SET", "key1", "foobar"}, "+OK\r\n"},
+		{[]string{"SET", "key2", "abcdef"}, "+OK\r\n"},
+		{[]string{"BITOP", "AND", "dest", "key1", "key2"}, ":6\r\n"},
+		{[]string{"GET", "dest"}, "$6\r\n`bc`ab\r\n"},
+		{[]string{"BITOP", "OR", "dest", "key1", "missing"}, ":6\r\n"},
+		{[]string{"GET", "dest"}, "$6\r\nfoobar\r\n"},
+		{[]string{"BITOP", "XOR", "dest", "key1", "key1", "active"}, ":6\r\n"},
+		{[]string{"GET", "dest"}, "$6\r\n\x01\x00@\x00\x00\x00\r\n"},
+		{[]string{"BITOP", "NOT", "dest", "mykey"}, ":3\r\n"},
+		{[]string{"GET", "dest"}, "$3\r\n\x00\x00\x00\r\n"},
+		{[]string{"BITOP", "NOT", "dest", "key1", "key2"}, "-ERR BITOP NOT must be called with a single source key.\r\n"},
+		{[]string{"BITOP", "NAND", "dest", "key1"}, "-ERR syntax error\r\n"},
+		{[]string{"BITOP", "AND", "dest", "missing"}, ":0\r\n"},
+		{[]string{"EXISTS", "dest"}, ":0\r\n"},
+		{[]string{"RPUSH", "list", "a"}, ":1\r\n"},
+		{[]string{"BITOP", "AND", "dest", "key1", "list"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"SETBIT", "list", "0", "1"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+	})
+}
+
+func Test_bitfield(t *testing.T) {
+	runCommands(t, NewServer(":0"), []commandTest{
+		{[]string{"BITFIELD", "mykey", "INCRBY", "i5", "100", "1", "GET", "u4", "0"}, "*2\r\n:1\r\n:0\r\n"},
+		{[]string{"STRLEN", "mykey"}, ":14\r\n"},
+		{[]string{"BITFIELD", "counters", "SET", "u8", "#1", "200", "GET", "u8", "8", "GET", "i8", "#1"}, "*3\r\n:0\r\n:200\r\n:-56\r\n"},
+		{[]string{"GET", "counters"}, "$2\r\n\x00\xc8\r\n"},
+		{[]string{"BITFIELD", "counters", "INCRBY", "u8", "#1", "100"}, "*1\r\n:44\r\n"},
+		{[]string{"BITFIELD", "counters", "OVERFLOW", "SAT", "INCRBY", "u8", "#1", "300", "INCRBY", "i8", "#1", "-300"}, "*2\r\n:255\r\n:-128\r\n"},
+		{[]string{"BITFIELD", "counters", "OVERFLOW", "FAIL", "INCRBY", "u8", "#1", "200", "GET", "u8", "#1"}, "*2\r\n$-1\r\n:128\r\n"},
+		{[]string{"BITFIELD", "counters", "OVERFLOW", "fail", "SET", "i4", "0", "8", "OVERFLOW", "WRAP", "SET", "i4", "0", "8", "GET", "i4", "0"}, "*3\r\n$-1\r\n:0\r\n:-8\r\n"},
+		{[]string{"BITFIELD", "big", "SET", "i64", "0", "9223372036854775807", "INCRBY", "i64", "0", "1"}, "*2\r\n:0\r\n:-9223372036854775808\r\n"},
+		{[]string{"BITFIELD", "big", "OVERFLOW", "SAT", "INCRBY", "i64", "0", "-1", "INCRBY", "i64", "0", "-1", "INCRBY", "i64", "0", "1"}, "*3\r\n:-9223372036854775808\r\n:-9223372036854775808\r\n:-9223372036854775807\r\n"},
+		{[]string{"BITFIELD", "big", "OVERFLOW", "SAT", "SET", "u63", "0", "-1", "GET", "u63", "0"}, "*2\r\n:4611686018427387904\r\n:9223372036854775807\r\n"},
+		{[]string{"BITFIELD", "missing", "GET", "u8", "0"}, "*1\r\n:0\r\n"},
+		{[]string{"EXISTS", "missing"}, ":0\r\n"},
+		{[]string{"BITFIELD", "mykey"}, "*0\r\n"},
+		{[]string{"BITFIELD_RO", "counters", "GET", "u8", "#1"}, "*1\r\n:128\r\n"},
+		{[]string{"BITFIELD_RO", "counters", "SET", "u8", "#1", "1"}, "-ERR BITFIELD_RO only supports the GET subcommand\r\n"},
+		{[]string{"BITFIELD", "counters", "GET", "u64", "0"}, "-ERR Invalid bitfield type. Use something like i16 u8. Note that u64 is not supported but i64 is.\r\n"},
+		{[]string{"BITFIELD", "counters", "GET", "I8", "0"}, "-ERR Invalid bitfield type. Use something like i16 u8. Note that u64 is not supported but i64 is.\r\n"},
+		{[]string{"BITFIELD", "counters", "GET", "u8", "-1"}, "-ERR bit offset is not an integer or out of range\r\n"},
+		{[]string{"BITFIELD", "counters", "GET", "u8", "#-1"}, "-ERR bit offset is not an integer or out of range\r\n"},
+		{[]string{"BITFIELD", "counters", "OVERFLOW", "MAYBE"}, "-ERR Invalid OVERFLOW type specified\r\n"},
+		{[]string{"BITFIELD", "counters", "GET", "u8"}, "-ERR syntax error\r\n"},
+		{[]string{"BITFIELD", "counters", "SET", "u8", "0", "x"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"RPUSH", "list", "a"}, ":1\r\n"},
+		{[]string{"BITFIELD", "list", "GET", "u8", "0"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+	})
+}
+
+func Test_bigNumbers(t *testing.T) {
+	runCommands(t, NewServer(":0"), []commandTest{
+		{[]string{"SET", "balance", "9223372036854775807", "EX", "100"}, "+OK\r\n"},
+		{[]string{"INCR", "balance"}, "-ERR increment or decrement would overflow\r\n"},
+		{[]string{"BIGINCR", "balance"}, "$19\r\n9223372036854775808\r\n"},
+		{[]string{"BIGINCRBY", "balance", "100000000000000000000"}, "$21\r\n109223372036854775808\r\n"},
+		{[]string{"GET", "balance"}, "$21\r\n109223372036854775808\r\n"},
+		{[]string{"BIGDECRBY", "balance", "109223372036854775808"}, "$1\r\n0\r\n"},
+		{[]string{"BIGDECR", "balance"}, "$2\r\n-1\r\n"},
+		{[]string{"INCR", "balance"}, ":0\r\n"},
+		{[]string{"BIGGET", "missing"}, "$-1\r\n"},
+		{[]string{"BIGDECR", "missing"}, "$2\r\n-1\r\n"},
+		{[]string{"BIGINCRBY", "balance", "007"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"BIGINCRBY", "balance", "-0"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"BIGINCRBY", "balance", "+1"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"SET", "name", "alice"}, "+OK\r\n"},
+		{[]string{"BIGGET", "name"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"BIGINCR", "name"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"RPUSH", "list", "a"}, ":1\r\n"},
+		{[]string{"BIGINCR", "list"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"HELLO", "3"}, "%7\r\n$6\r\nserver\r\n$5\r\nredis\r\n$7\r\nversion\r\n$5\r\n7.2.0\r\n$5\r\nproto\r\n:3\r\n" +
+			"$2\r\nid\r\n:1\r\n$4\r\nmode\r\n$10\r\nstandalone\r\n$4\r\nrole\r\n$6\r\nmaster\r\n$7\r\nmodules\r\n*0\r\n"},
+		{[]string{"BIGINCRBY", "balance", "-123456789012345678901234567890"}, "(-123456789012345678901234567890\r\n"},
+		{[]string{"BIGGET", "balance"}, "(-123456789012345678901234567890\r\n"},
+	})
+}
+
+func Test_lists(t *testing.T) {
+	runCommands(t, NewServer(":0"), []commandTest{
+		{[]string{"RPUSH", "mylist", "a", "b", "c", "d", "e"}, ":5\r\n"},
+		{[]string{"LLEN", "mylist"}, ":5\r\n"},
+		{[]string{"LLEN", "missing"}, ":0\r\n"},
+		{[]string{"LRANGE", "mylist", "0", "-1"}, "*5\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n$1\r\nd\r\n$1\r\ne\r\n"},
+		{[]string{"LRANGE", "mylist", "-100", "1"}, "*2\r\n$1\r\na\r\n$1\r\nb\r\n"},
+		{[]string{"LRANGE", "mylist", "3", "100"}, "*2\r\n$1\r\nd\r\n$1\r\ne\r\n"},
+		{[]string{"LRANGE", "mylist", "4", "2"}, "*0\r\n"},
+		{[]string{"LRANGE", "mylist", "0", "x"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"LRANGE", "missing", "0", "-1"}, "*0\r\n"},
+		{[]string{"LINDEX", "mylist", "-1"}, "$1\r\ne\r\n"},
+		{[]string{"LINDEX", "mylist", "1"}, "$1\r\nb\r\n"},
+		{[]string{"LINDEX", "mylist", "5"}, "$-1\r\n"},
+		{[]string{"LINDEX", "mylist", "-6"}, "$-1\r\n"},
+		{[]string{"LINDEX", "missing", "x"}, "$-1\r\n"},
+		{[]string{"LSET", "mylist", "-2", "D"}, "+OK\r\n"},
+		{[]string{"LSET", "mylist", "5", "x"}, "-ERR index out of range\r\n"},
+		{[]string{"LSET", "missing", "0", "x"}, "-ERR no such key\r\n"},
+		{[]string{"LINSERT", "mylist", "BEFORE", "a", "_"}, ":6\r\n"},
+		{[]string{"LINSERT", "mylist", "after", "e", "f"}, ":7\r\n"},
+		{[]string{"LINSERT", "mylist", "AFTER", "c", "c"}, ":8\r\n"},
+		{[]string{"LINSERT", "mylist", "BEFORE", "z", "x"}, ":-1\r\n"},
+		{[]string{"LINSERT", "missing", "BEFORE", "z", "x"}, ":0\r\n"},
+		{[]string{"LINSERT", "mylist", "ABOVE", "a", "x"}, "-ERR syntax error\r\n"},
+		{[]string{"LRANGE", "mylist", "0", "-1"}, "*8\r\n$1\r\n_\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n$1\r\nc\r\n$1\r\nD\r\n$1\r\ne\r\n$1\r\nf\r\n"},
+
+		{[]string{"RPUSH", "dups", "a", "b", "a", "c", "a", "b", "a"}, ":7\r\n"},
+		{[]string{"LPOS", "dups", "a"}, ":0\r\n"},
+		{[]string{"LPOS", "dups", "a", "RANK", "2"}, ":2\r\n"},
+		{[]string{"LPOS", "dups", "a", "RANK", "-1"}, ":6\r\n"},
+		{[]string{"LPOS", "dups", "a", "COUNT", "0"}, "*4\r\n:0\r\n:2\r\n:4\r\n:6\r\n"},
+		{[]string{"LPOS", "dups", "a", "RANK", "-2", "COUNT", "2"}, "*2\r\n:4\r\n:2\r\n"},
+		{[]string{"LPOS", "dups", "a", "COUNT", "0", "MAXLEN", "3"}, "*2\r\n:0\r\n:2\r\n"},
+		{[]string{"LPOS", "dups", "c", "MAXLEN", "3"}, "$-1\r\n"},
+		{[]string{"LPOS", "dups", "z", "COUNT", "1"}, "*0\r\n"},
+		{[]string{"LPOS", "missing", "z"}, "$-1\r\n"},
+		{[]string{"LPOS", "missing", "z", "COUNT", "1"}, "*0\r\n"},
+		{[]string{"LPOS", "dups", "a", "RANK", "0"}, "-ERR RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list\r\n"},
+		{[]string{"LPOS", "dups", "a", "COUNT", "-1"}, "-ERR COUNT can't be negative\r\n"},
+		{[]string{"LPOS", "dups", "a", "MAXLEN", "-1"}, "-ERR MAXLEN can't be negative\r\n"},
+		{[]string{"LPOS", "dups", "a", "RANK"}, "-ERR syntax error\r\n"},
+
+		{[]string{"LREM", "dups", "-2", "a"}, ":2\r\n"},
+		{[]string{"LRANGE", "dups", "0", "-1"}, "*5\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\na\r\n$1\r\nc\r\n$1\r\nb\r\n"},
+		{[]string{"LREM", "dups", "1", "b"}, ":1\r\n"},
+		{[]string{"LREM", "dups", "0", "a"}, ":2\r\n"},
+		{[]string{"LRANGE", "dups", "0", "-1"}, "*2\r\n$1\r\nc\r\n$1\r\nb\r\n"},
+		{[]string{"LREM", "dups", "0", "b"}, ":1\r\n"},
+		{[]string{"LREM", "dups", "0", "c"}, ":1\r\n"},
+		{[]string{"EXISTS", "dups"}, ":0\r\n"},
+
+		{[]string{"LTRIM", "mylist", "1", "-2"}, "+OK\r\n"},
+		{[]string{"LRANGE", "mylist", "0", "-1"}, "*6\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n$1\r\nc\r\n$1\r\nD\r\n$1\r\ne\r\n"},
+		{[]string{"LTRIM", "mylist", "-3", "100"}, "+OK\r\n"},
+		{[]string{"LRANGE", "mylist", "0", "-1"}, "*3\r\n$1\r\nc\r\n$1\r\nD\r\n$1\r\ne\r\n"},
+		{[]string{"LTRIM", "missing", "0", "1"}, "+OK\r\n"},
+		{[]string{"LTRIM", "mylist", "2", "1"}, "+OK\r\n"},
+		{[]string{"EXISTS", "mylist"}, ":0\r\n"},
+
+		{[]string{"SET", "str", "a"}, "+OK\r\n"},
+		{[]string{"LRANGE", "str", "0", "-1"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"LINDEX", "str", "0"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"LPOS", "str", "a"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+	})
+}
+
+func Test_listPops(t *testing.T) {
+	runCommands(t, NewServer(":0"), []commandTest{
+		{[]string{"RPUSH", "jobs", "1", "2", "3", "4", "5"}, ":5\r\n"},
+		{[]string{"LPOP", "jobs"}, "$1\r\n1\r\n"},
+		{[]string{"RPOP", "jobs"}, "$1\r\n5\r\n"},
+		{[]string{"LPOP", "jobs", "0"}, "*0\r\n"},
+		{[]string{"RPOP", "jobs", "2"}, "*2\r\n$1\r\n4\r\n$1\r\n3\r\n"},
+		{[]string{"LPOP", "jobs", "10"}, "*1\r\n$1\r\n2\r\n"},
+		{[]string{"EXISTS", "jobs"}, ":0\r\n"},
+		{[]string{"LPOP", "jobs"}, "$-1\r\n"},
+		{[]string{"LPOP", "jobs", "1"}, "*-1\r\n"},
+		{[]string{"LPOP", "jobs", "-1"}, "-ERR value is out of range, must be positive\r\n"},
+		{[]string{"LPOP", "jobs", "1", "2"}, "-ERR wrong number of arguments for 'lpop' command\r\n"},
+
+		{[]string{"LPUSHX", "jobs", "a"}, ":0\r\n"},
+		{[]string{"EXISTS", "jobs"}, ":0\r\n"},
+		{[]string{"RPUSH", "jobs", "b"}, ":1\r\n"},
+		{[]string{"LPUSHX", "jobs", "a", "_"}, ":3\r\n"},
+		{[]string{"RPUSHX", "jobs", "c"}, ":4\r\n"},
+		{[]string{"LRANGE", "jobs", "0", "-1"}, "*4\r\n$1\r\n_\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+
+		{[]string{"LMOVE", "jobs", "jobs", "LEFT", "RIGHT"}, "$1\r\n_\r\n"},
+		{[]string{"LRANGE", "jobs", "0", "-1"}, "*4\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n$1\r\n_\r\n"},
+		{[]string{"RPOPLPUSH", "jobs", "done"}, "$1\r\n_\r\n"},
+		{[]string{"LMOVE", "jobs", "done", "left", "right"}, "$1\r\na\r\n"},
+		{[]string{"LRANGE", "done", "0", "-1"}, "*2\r\n$1\r\n_\r\n$1\r\na\r\n"},
+		{[]string{"LMOVE", "jobs", "done", "LEFT", "UP"}, "-ERR syntax error\r\n"},
+		{[]string{"LMOVE", "missing", "done", "LEFT", "LEFT"}, "$-1\r\n"},
+		{[]string{"SET", "str", "x"}, "+OK\r\n"},
+		{[]string{"LMOVE", "jobs", "str", "LEFT", "LEFT"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"LLEN", "jobs"}, ":2\r\n"},
+
+		{[]string{"LMPOP", "3", "missing", "jobs", "done", "RIGHT"}, "*2\r\n$4\r\njobs\r\n*1\r\n$1\r\nc\r\n"},
+		{[]string{"LMPOP", "2", "missing", "done", "LEFT", "COUNT", "5"}, "*2\r\n$4\r\ndone\r\n*2\r\n$1\r\n_\r\n$1\r\na\r\n"},
+		{[]string{"LMPOP", "2", "missing", "done", "LEFT"}, "*-1\r\n"},
+		{[]string{"LMPOP", "0", "jobs", "LEFT"}, "-ERR numkeys should be greater than 0\r\n"},
+		{[]string{"LMPOP", "2", "jobs", "LEFT"}, "-ERR syntax error\r\n"},
+		{[]string{"LMPOP", "1", "jobs", "LEFT", "COUNT", "0"}, "-ERR count should be greater than 0\r\n"},
+		{[]string{"LMPOP", "1", "jobs", "LEFT", "COUNT", "1", "COUNT", "1"}, "-ERR syntax error\r\n"},
+		{[]string{"LMPOP", "2", "str", "jobs", "LEFT"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"COMMAND", "GETKEYS", "LMPOP", "2", "a", "b", "LEFT", "COUNT", "3"}, "*2\r\n$1\r\na\r\n$1\r\nb\r\n"},
+		{[]string{"COMMAND", "GETKEYS", "LMPOP", "4", "a", "b", "LEFT"}, "-ERR The command has no key arguments\r\n"},
+	})
+}
+
+func Test_blockingPops(t *testing.T) {
+	s := NewServer(":0")
+	c1, c2, c3 := new(testPeer), new(testPeer), new(testPeer)
+	send := func(p *testPeer, args ...string) {
+		t.Helper()
+		s.HandleMessage(transport.Message{Peer: p, Payload: request(args...)})
+	}
+	expect := func(p *testPeer, name, want string) {
+		t.Helper()
+		if got := p.sent.String(); got != want {
+			t.Errorf("%s received %q, want %q", name, got, want)
+		}
+		p.sent.Reset()
+	}
+
+	// Clients are served in the order they blocked,
+	// and requests sent while blocked run afterwards.
+	send(c1, "BLPOP", "q1", "q2", "0")
+	send(c2, "BRPOP", "q2", "0")
+	send(c1, "PING")
+	expect(c1, "blocked client", "")
+	send(c3, "RPUSH", "q2", "a", "b")
+	expect(c3, "pusher", ":2\r\n")
+	expect(c1, "first blocked client", "*2\r\n$2\r\nq2\r\n$1\r\na\r\n+PONG\r\n")
+	expect(c2, "second blocked client", "*2\r\n$2\r\nq2\r\n$1\r\nb\r\n")
+	send(c3, "EXISTS", "q2")
+	expect(c3, "pusher", ":0\r\n")
+
+	// Elements moved by BLMOVE serve the clients blocked on the destination.
+	send(c1, "BLMOVE", "src", "dst", "RIGHT", "LEFT", "0")
+	send(c2, "BLPOP", "dst", "0")
+	send(c3, "LPUSH", "src", "x")
+	expect(c1, "BLMOVE client", "$1\r\nx\r\n")
+	expect(c2, "BLPOP client", "*2\r\n$3\r\ndst\r\n$1\r\nx\r\n")
+
+	send(c1, "BLMPOP", "0", "2", "a", "b", "LEFT", "COUNT", "2")
+	send(c3, "SET", "a", "str")
+	expect(c1, "BLMPOP client", "")
+	send(c3, "DEL", "a")
+	send(c3, "RPUSH", "b", "1", "2", "3")
+	expect(c1, "BLMPOP client", "*2\r\n$1\r\nb\r\n*2\r\n$1\r\n1\r\n$1\r\n2\r\n")
+
+	// Lists holding elements are popped from without blocking.
+	send(c1, "BLPOP", "missing", "b", "0")
+	expect(c1, "BLPOP client", "*2\r\n$1\r\nb\r\n$1\r\n3\r\n")
+
+	send(c1, "BRPOP", "q", "0.01")
+	b := <-s.timeoutCh
+	s.timeoutBlocked(b)
+	expect(c1, "timed out client", "*-1\r\n")
+
+	send(c1, "BLPOP", "q", "0")
+	s.HandleMessage(transport.Message{Peer: c1, Err: io.EOF})
+	if len(s.blockingKeys) != 0 || !c1.closed {
+		t.Errorf("disconnected client is still blocked on %v", s.blockingKeys)
+	}
+
+	runCommands(t, s, []commandTest{
+		{[]string{"BLPOP", "q", "-1"}, "-ERR timeout is negative\r\n"},
+		{[]string{"BLPOP", "q", "soon"}, "-ERR timeout is not a float or out of range\r\n"},
+		{[]string{"BLPOP", "q", "1e20"}, "-ERR timeout is out of range\r\n"},
+		{[]string{"SET", "a", "str"}, "+OK\r\n"},
+		{[]string{"BLPOP", "a", "0"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"BLMOVE", "q", "dst", "UP", "LEFT", "0"}, "-ERR syntax error\r\n"},
+		{[]string{"BLMPOP", "0", "0", "q", "LEFT"}, "-ERR numkeys should be greater than 0\r\n"},
+		{[]string{"COMMAND", "GETKEYS", "BLMPOP", "0", "2", "a", "b", "LEFT"}, "*2\r\n$1\r\na\r\n$1\r\nb\r\n"},
+		{[]string{"COMMAND", "GETKEYS", "BLPOP", "a", "b", "0"}, "*2\r\n$1\r\na\r\n$1\r\nb\r\n"},
+	})
+}
+
+func Test_parseTimeout(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		s    string
+		want time.Duration
+	}{
+		{"0", 0},
+		{"1", time.Second},
+		{"0.5", 500 * time.Millisecond},
+		{"0.0001", time.Millisecond},
+		{"1e9", 1e9 * time.Second},
+	}
+	for _, tt := range tests {
+		if got, err := parseTimeout(tt.s, now); err != nil || got != tt.want {
+			t.Errorf("parseTimeout(%q) = %v, %v, want %v", tt.s, got, err, tt.want)
+		}
+	}
+}
+
+func Test_readMissingContainers(t *testing.T) {
+	s := NewServer(":0")
+	runCommands(t, s, []commandTest{
+		{[]string{"LRANGE", "missing", "0", "-1"}, "*0\r\n"},
+		{[]string{"LLEN", "missing"}, ":0\r\n"},
+		{[]string{"LREM", "missing", "0", "a"}, ":0\r\n"},
+		{[]string{"HGETALL", "missing"}, "*0\r\n"},
+		{[]string{"HMGET", "missing", "f"}, "*1\r\n$-1\r\n"},
+		{[]string{"HSTRLEN", "missing", "f"}, ":0\r\n"},
+		{[]string{"HLEN", "missing"}, ":0\r\n"},
+		{[]string{"EXISTS", "missing"}, ":0\r\n"},
+	})
+	// Reads of missing keys allocate no container.
+	db := s.dbs[0]
+	if n := testing.AllocsPerRun(100, func() {
+		lookupList(db, "missing")
+		lookupHash(db, "missing")
+	}); n != 0 {
+		t.Errorf("looking missing keys up allocated %v times", n)
+	}
+}
+
+// sortedReply sorts the elements of an array of bulk strings, taken in
+// groups of size, for replies that come in no particular order.
+func sortedReply(reply string, size int) string {
+	header, rest, _ := strings.Cut(reply, "\r\n")
+	lines := strings.SplitAfter(rest, "\r\n")
+	var groups []string
+	for i := 0; i+2*size <= len(lines); i += 2 * size {
+		groups = append(groups, strings.Join(lines[i:i+2*size], ""))
+	}
+	slices.Sort(groups)
+	return header + "\r\n" + strings.Join(groups, "")
+}
+
+func Test_hashes(t *testing.T) {
+	wrongType := "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"
+	s := NewServer(":0")
+	runCommands(t, s, []commandTest{
+		{[]string{"HSET", "user", "name", "alice", "age", "31", "city", "paris"}, ":3\r\n"},
+	})
+	peer := new(testPeer)
+	for _, tt := range []struct {
+		args []string
+		size int
+		want string
+	}{
+		{[]string{"HKEYS", "user"}, 1, "*3\r\n$3\r\nage\r\n$4\r\ncity\r\n$4\r\nname\r\n"},
+		{[]string{"HVALS", "user"}, 1, "*3\r\n$2\r\n31\r\n$5\r\nalice\r\n$5\r\nparis\r\n"},
+		{[]string{"HGETALL", "user"}, 2, "*6\r\n$3\r\nage\r\n$2\r\n31\r\n$4\r\ncity\r\n$5\r\nparis\r\n$4\r\nname\r\n$5\r\nalice\r\n"},
+	} {
+		peer.sent.Reset()
+		s.HandleMessage(transport.Message{Peer: peer, Payload: request(tt.args...)})
+		if got := sortedReply(peer.sent.String(), tt.size); got != tt.want {
+			t.Errorf("%q = %q sorted, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	runCommands(t, NewServer(":0"), []commandTest{
+		{[]string{"HSET", "user", "name", "alice", "age", "30"}, ":2\r\n"},
+		{[]string{"HSET", "user", "age", "31", "city", "paris"}, ":1\r\n"},
+		{[]string{"HSET", "user", "name"}, "-ERR wrong number of arguments for 'hset' command\r\n"},
+		{[]string{"HSETNX", "user", "name", "bob"}, ":0\r\n"},
+		{[]string{"HSETNX", "user", "email", "a@b.c"}, ":1\r\n"},
+		{[]string{"HGET", "user", "age"}, "$2\r\n31\r\n"},
+		{[]string{"HGET", "user", "missing"}, "$-1\r\n"},
+		{[]string{"HGET", "missing", "name"}, "$-1\r\n"},
+		{[]string{"HMGET", "user", "name", "missing", "city"}, "*3\r\n$5\r\nalice\r\n$-1\r\n$5\r\nparis\r\n"},
+		{[]string{"HLEN", "user"}, ":4\r\n"},
+		{[]string{"HEXISTS", "user", "email"}, ":1\r\n"},
+		{[]string{"HSTRLEN", "user", "city"}, ":5\r\n"},
+		{[]string{"HSTRLEN", "user", "missing"}, ":0\r\n"},
+		{[]string{"HDEL", "user", "email", "missing"}, ":1\r\n"},
+		{[]string{"HEXISTS", "user", "email"}, ":0\r\n"},
+		{[]string{"HGETALL", "missing"}, "*0\r\n"},
+		{[]string{"HKEYS", "missing"}, "*0\r\n"},
+
+		{[]string{"HINCRBY", "user", "age", "-1"}, ":30\r\n"},
+		{[]string{"HINCRBY", "user", "visits", "5"}, ":5\r\n"},
+		{[]string{"HINCRBY", "user", "name", "1"}, "-ERR hash value is not an integer\r\n"},
+		{[]string{"HINCRBY", "user", "age", "x"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"HSET", "user", "big", "9223372036854775807"}, ":1\r\n"},
+		{[]string{"HINCRBY", "user", "big", "1"}, "-ERR increment or decrement would overflow\r\n"},
+		{[]string{"HINCRBYFLOAT", "user", "age", "0.5"}, "$4\r\n30.5\r\n"},
+		{[]string{"HINCRBYFLOAT", "user", "score", "1.0e3"}, "$4\r\n1000\r\n"},
+		{[]string{"HINCRBYFLOAT", "user", "score", "inf"}, "-ERR value is NaN or Infinity\r\n"},
+		{[]string{"HINCRBYFLOAT", "user", "score", "x"}, "-ERR value is not a valid float\r\n"},
+		{[]string{"HINCRBYFLOAT", "user", "name", "1"}, "-ERR hash value is not a float\r\n"},
+		{[]string{"HDEL", "user", "visits", "big", "score"}, ":3\r\n"},
+
+		{[]string{"HDEL", "user", "name", "age", "city"}, ":3\r\n"},
+		{[]string{"EXISTS", "user"}, ":0\r\n"},
+		{[]string{"HDEL", "user", "name"}, ":0\r\n"},
+
+		{[]string{"SET", "str", "v"}, "+OK\r\n"},
+		{[]string{"RPUSH", "list", "a"}, ":1\r\n"},
+		{[]string{"HSET", "str", "f", "v"}, wrongType},
+		{[]string{"HGET", "list", "f"}, wrongType},
+		{[]string{"HGETALL", "str"}, wrongType},
+		{[]string{"HINCRBY", "list", "f", "1"}, wrongType},
+		{[]string{"HRANDFIELD", "str"}, wrongType},
+		{[]string{"HSCAN", "list", "0"}, wrongType},
+		{[]string{"HSET", "h", "f", "v"}, ":1\r\n"},
+		{[]string{"GET", "h"}, wrongType},
+		{[]string{"LPUSH", "h", "a"}, wrongType},
+		{[]string{"LLEN", "h"}, wrongType},
+	})
+}
+
+func Test_hrandfield(t *testing.T) {
+	runCommands(t, NewServer(":0"), []commandTest{
+		{[]string{"HSET", "one", "f", "v"}, ":1\r\n"},
+		{[]string{"SET", "str", "v"}, "+OK\r\n"},
+		{[]string{"HRANDFIELD", "one"}, "$1\r\nf\r\n"},
+		{[]string{"HRANDFIELD", "one", "5"}, "*1\r\n$1\r\nf\r\n"},
+		{[]string{"HRANDFIELD", "one", "-3"}, "*3\r\n$1\r\nf\r\n$1\r\nf\r\n$1\r\nf\r\n"},
+		{[]string{"HRANDFIELD", "one", "-2", "withvalues"}, "*4\r\n$1\r\nf\r\n$1\r\nv\r\n$1\r\nf\r\n$1\r\nv\r\n"},
+		{[]string{"HRANDFIELD", "one", "0"}, "*0\r\n"},
+		{[]string{"HRANDFIELD", "missing"}, "$-1\r\n"},
+		{[]string{"HRANDFIELD", "missing", "3"}, "*0\r\n"},
+		{[]string{"HRANDFIELD", "one", "1", "VALUES"}, "-ERR syntax error\r\n"},
+		{[]string{"HRANDFIELD", "one", "1", "WITHVALUES", "x"}, "-ERR syntax error\r\n"},
+		{[]string{"HRANDFIELD", "one", "x"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"HRANDFIELD", "one", "-9223372036854775807", "WITHVALUES"}, "-ERR value is out of range\r\n"},
+		// Unlike redis, replies of more repeated fields than a request may
+		// carry arguments are refused, as they are built whole.
+		{[]string{"HRANDFIELD", "one", "-9223372036854775807"}, "-ERR value is out of range\r\n"},
+		{[]string{"HRANDFIELD", "one", "-2000000"}, "-ERR value is out of range\r\n"},
+		{[]string{"HRANDFIELD", "one", "-600000", "WITHVALUES"}, "-ERR value is out of range\r\n"},
+		{[]string{"HRANDFIELD", "missing", "-2000000"}, "*0\r\n"},
+		{[]string{"HRANDFIELD", "str", "-2000000"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"HRANDFIELD", "one", "9223372036854775807"}, "*1\r\n$1\r\nf\r\n"},
+		{[]string{"HELLO", "3"}, "%7\r\n$6\r\nserver\r\n$5\r\nredis\r\n$7\r\nversion\r\n$5\r\n7.2.0\r\n$5\r\nproto\r\n:3\r\n" +
+			"$2\r\nid\r\n:1\r\n$4\r\nmode\r\n$10\r\nstandalone\r\n$4\r\nrole\r\n$6\r\nmaster\r\n$7\r\nmodules\r\n*0\r\n"},
+		{[]string{"HRANDFIELD", "one", "1", "WITHVALUES"}, "*1\r\n*2\r\n$1\r\nf\r\n$1\r\nv\r\n"},
+		{[]string{"HRANDFIELD", "missing"}, "_\r\n"},
+		{[]string{"HGETALL", "one"}, "%1\r\n$1\r\nf\r\n$1\r\nv\r\n"},
+	})
+
+	// Positive counts pick distinct fields.
+	s := NewServer(":0")
+	runCommands(t, s, []commandTest{
+		{[]string{"HSET", "h", "a", "1", "b", "2", "c", "3", "d", "4"}, ":4\r\n"},
+	})
+	peer := new(testPeer)
+	for i := 0; i < 20; i++ {
+		s.HandleMessage(transport.Message{Peer: peer, Payload: request("HRANDFIELD", "h", "3")})
+	}
+	replies := strings.Split(peer.sent.String(), "*3\r\n")
+	for _, r := range replies[1:] {
+		fields := strings.Split(strings.TrimSuffix(r, "\r\n"), "\r\n")
+		if seen := map[string]bool{fields[1]: true, fields[3]: true, fields[5]: true}; len(seen) != 3 {
+			t.Errorf("HRANDFIELD h 3 = %q, want distinct fields", fields)
+		}
+	}
+	if len(replies) != 21 {
+		t.Errorf("HRANDFIELD h 3 replied %q", peer.sent.String())
+	}
+}
+
+func Test_hscan(t *testing.T) {
+	runCommands(t, NewServer(":0"), []commandTest{
+		{[]string{"HSET", "h", "f1", "1", "f2", "2", "f3", "3", "f4", "4", "f5", "5", "name", "x"}, ":6\r\n"},
+		{[]string{"HSCAN", "h", "0", "MATCH", "f[3]"}, "*2\r\n$1\r\n0\r\n*2\r\n$2\r\nf3\r\n$1\r\n3\r\n"},
+		{[]string{"HSCAN", "h", "0", "MATCH", "n*", "NOVALUES"}, "*2\r\n$1\r\n0\r\n*1\r\n$4\r\nname\r\n"},
+		{[]string{"HSCAN", "missing", "0", "COUNT", "0"}, "*2\r\n$1\r\n0\r\n*0\r\n"},
+		{[]string{"HSCAN", "h", "x"}, "-ERR invalid cursor\r\n"},
+		{[]string{"HSCAN", "h", "0", "COUNT", "0"}, "-ERR syntax error\r\n"},
+		{[]string{"HSCAN", "h", "0", "COUNT", "x"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"HSCAN", "h", "0", "MATCH"}, "-ERR syntax error\r\n"},
+		{[]string{"HSCAN", "h", "0", "WITHVALUES"}, "-ERR syntax error\r\n"},
+	})
+}
+
+func Test_hashScan(t *testing.T) {
+	// Fields there from the start to the end of a scan are returned, however
+	// many fields are added or removed, and the hash rehashed, in between.
+	for _, tt := range []struct {
+		name       string
+		add, del   int
+		start, end int
+	}{
+		{"unchanged", 0, 0, 0, 1000},
+		{"growing", 5000, 0, 0, 1000},
+		{"shrinking", 0, 950, 950, 1000},
+	} {
+		h := newHash()
+		for i := 0; i < 1000; i++ {
+			h.set(fmt.Sprintf("field:%d", i), "v")
+		}
+		seen := map[string]bool{}
+		var fields []string
+		cursor, calls := uint64(0), 0
+		for {
+			fields, cursor = h.scan(cursor, 10)
+			for _, f := range fields {
+				seen[f] = true
+			}
+			if calls++; calls == 20 {
+				for i := 0; i < tt.add; i++ {
+					h.set(fmt.Sprintf("added:%d", i), "v")
+				}
+				for i := 0; i < tt.del; i++ {
+					h.del(fmt.Sprintf("field:%d", i))
+				}
+			}
+			if cursor == 0 {
+				break
+			}
+		}
+		for i := tt.start; i < tt.end; i++ {
+			if f := fmt.Sprintf("field:%d", i); !seen[f] {
+				t.Errorf("%s: scan missed %q", tt.name, f)
+			}
+		}
+		if len(h.fields) > len(h.buckets) || len(h.fields) < len(h.buckets)/8 {
+			t.Errorf("%s: %d fields in %d buckets", tt.name, len(h.fields), len(h.buckets))
+		}
+	}
+}
+
+func Test_stringMatch(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "", true},
+		{"*", "a/b", true},
+		{"user:*", "user:1", true},
+		{"user:*", "users", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h*llo", "heeello", true},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"h[c-a]llo", "hbllo", true},
+		{"h[a-c]llo", "hdllo", false},
+		{`h\*llo`, "h*llo", true},
+		{`h\*llo`, "hello", false},
+		{"[abc", "b", true},
+		{"a**b", "axxb", true},
+		{"a*b", "axxc", false},
+	}
+	for _, tt := range tests {
+		if got := stringMatch(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("stringMatch(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
@@ -1,103 +1,265 @@
 package redis
 
 import (
+	"bytes"
 	"fmt"
-	"strconv"
-	"strings"
 )
 
-func eatBulkString(data []byte, c int) (bytesAte int) {
-	t := string(data[c:])
-	// minimum valid empty string
-	if len(t) < len("$0\r\n\r\n") {
-		return
-	}
+const (
+	// maxMultibulkLen is the largest number of arguments a request may carry.
+	maxMultibulkLen = 1024 * 1024
+	// maxBulkLen is the largest bulk string a request may carry.
+	maxBulkLen = 512 * 1024 * 1024
+	// maxInlineLen is the longest inline request, or length header,
+	// accepted while no line terminator has been found.
+	maxInlineLen = 64 * 1024
+)
 
-	if rune(t[0]) != '$' {
-		return
-	}
+// protocolError is replied to a client sending a malformed request,
+// right before its connection is closed.
+type protocolError string
 
-	t = t[1:]
-	tSplit := strings.SplitN(t, "\r\n", 3)
-	if len(tSplit) < 3 {
-		return
+func (e protocolError) Error() string {
+	return "ERR Protocol error: " + string(e)
+}
+
+// parseRequest parses the request at the start of buf. Requests are either
+// arrays of bulk strings, as sent by redis clients in both RESP2 and RESP3,
+// or inline commands, as typed in telnet. Nothing is copied: the returned
+// arguments point into buf and n is the number of bytes the request took.
+// A request without arguments (an empty line or "*0\r\n") is valid and
+// yields no args.
+//
+// When buf does not hold a complete request yet, n is 0 and need is the
+// number of bytes that must at least be appended to buf before parsing it
+// again can make progress.
+func parseRequest(buf []byte) (args [][]byte, n int, need int, err error) {
+	if len(buf) == 0 {
+		return nil, 0, 1, nil
+	}
+	if buf[0] == '*' {
+		return parseMultibulk(buf)
 	}
+	return parseInline(buf)
+}
 
-	tLen, err := strconv.Atoi(tSplit[0])
+func parseInline(buf []byte) (args [][]byte, n int, need int, err error) {
+	i := bytes.IndexByte(buf, '\n')
+	if i < 0 {
+		if len(buf) > maxInlineLen {
+			return nil, 0, 0, protocolError("too big inline request")
+		}
+		return nil, 0, 1, nil
+	}
+	line := bytes.TrimSuffix(buf[:i], []byte("\r"))
+	args, err = splitArgs(line)
 	if err != nil {
-		return
+		return nil, 0, 0, err
 	}
+	return args, i + 1, 0, nil
+}
 
-	if tLen != len(tSplit[1]) {
-		return
-	}
+// splitArgs splits an inline command into arguments, following the rules
+// of redis-cli: arguments are separated by any run of whitespace, and can
+// be quoted. Double quoted arguments support the escapes \n, \r, \t, \b,
+// \a and \xHH, single quoted ones only \'. A closing quote must be followed
+// by whitespace or the end of the line.
+func splitArgs(line []byte) ([][]byte, error) {
+	var args [][]byte
+	p := 0
+	for {
+		for p < len(line) && isSpace(line[p]) {
+			p++
+		}
+		if p == len(line) {
+			return args, nil
+		}
 
-	bytesAte = len(fmt.Sprintf("$%s\r\n%s\r\n", tSplit[0], tSplit[1]))
-	return
+		// inq and insq are set while in a double or single quoted part.
+		inq, insq := false, false
+		current := make([]byte, 0, 16)
+		for done := false; !done; {
+			switch {
+			case inq:
+				if p == len(line) {
+					return nil, errorUnbalancedQuotes
+				}
+				if line[p] == '\\' && p+3 < len(line) && line[p+1] == 'x' && isHexDigit(line[p+2]) && isHexDigit(line[p+3]) {
+					current = append(current, hexDigit(line[p+2])<<4|hexDigit(line[p+3]))
+					p += 3
+				} else if line[p] == '\\' && p+1 < len(line) {
+					p++
+					switch c := line[p]; c {
+					case 'n':
+						current = append(current, '\n')
+					case 'r':
+						current = append(current, '\r')
+					case 't':
+						current = append(current, '\t')
+					case 'b':
+						current = append(current, '\b')
+					case 'a':
+						current = append(current, '\a')
+					default:
+						current = append(current, c)
+					}
+				} else if line[p] == '"' {
+					// The closing quote must be followed by a space or nothing at all.
+					if p+1 < len(line) && !isSpace(line[p+1]) {
+						return nil, errorUnbalancedQuotes
+					}
+					done = true
+				} else {
+					current = append(current, line[p])
+				}
+			case insq:
+				if p == len(line) {
+					return nil, errorUnbalancedQuotes
+				}
+				if line[p] == '\\' && p+1 < len(line) && line[p+1] == '\'' {
+					p++
+					current = append(current, '\'')
+				} else if line[p] == '\'' {
+					if p+1 < len(line) && !isSpace(line[p+1]) {
+						return nil, errorUnbalancedQuotes
+					}
+					done = true
+				} else {
+					current = append(current, line[p])
+				}
+			default:
+				if p == len(line) {
+					done = true
+					break
+				}
+				switch line[p] {
+				case ' ', '\n', '\r', '\t', 0:
+					done = true
+				case '"':
+					inq = true
+				case '\'':
+					insq = true
+				default:
+					current = append(current, line[p])
+				}
+			}
+			if p < len(line) {
+				p++
+			}
+		}
+		args = append(args, current)
+	}
 }
 
-func eatArray(data []byte, c int) (bytesAte int) {
-	t := string(data[c:])
-	// minimum valid empty string
-	if len(t) < len("*0\r\n") {
-		return
-	}
+var errorUnbalancedQuotes = protocolError("unbalanced quotes in request")
 
-	if rune(t[0]) != '*' {
-		return
-	}
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\v' || c == '\f' || c == '\r'
+}
 
-	t = t[1:]
-	tSplit := strings.SplitN(t, "\r\n", 2)
-	if len(tSplit) < 2 {
-		return
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func hexDigit(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
 	}
+	return c - '0'
+}
 
-	tLen, err := strconv.Atoi(tSplit[0])
+func parseMultibulk(buf []byte) (args [][]byte, n int, need int, err error) {
+	l, pos, err := parseLength(buf)
 	if err != nil {
-		return
-	}
-	ct := 0
-	totalBulkStrings := 0
-	for totalBulkStrings < tLen {
-		cc := eatBulkString([]byte(tSplit[1][ct:]), 0)
-		if cc != 0 {
-			ct += cc
-			totalBulkStrings++
-			continue
+		if err == errorLengthTooBig {
+			return nil, 0, 0, protocolError("too big mbulk count string")
 		}
-		break
+		return nil, 0, 0, protocolError("invalid multibulk length")
 	}
-	ct += len(fmt.Sprintf("*%s\r\n", tSplit[0]))
-	if totalBulkStrings != tLen {
-		return
+	if pos == 0 {
+		return nil, 0, 1, nil
+	}
+	if l > maxMultibulkLen {
+		return nil, 0, 0, protocolError("invalid multibulk length")
+	}
+	if l <= 0 {
+		return nil, pos, 0, nil
 	}
-	bytesAte = c + ct
-	return
-}
 
-func parseBulkString(s string) (res string, err error) {
-	s = s[1:]
-	return strings.SplitN(s, "\r\n", 3)[1], nil
-}
+	args = make([][]byte, 0, min(l, 1024))
+	for len(args) < l {
+		if pos == len(buf) {
+			return nil, 0, 1, nil
+		}
+		if buf[pos] != '$' {
+			return nil, 0, 0, protocolError(fmt.Sprintf("expected '$', got '%c'", buf[pos]))
+		}
+		bl, hs, err := parseLength(buf[pos:])
+		if err != nil {
+			if err == errorLengthTooBig {
+				return nil, 0, 0, protocolError("too big bulk count string")
+			}
+			return nil, 0, 0, protocolError("invalid bulk length")
+		}
+		if hs == 0 {
+			return nil, 0, 1, nil
+		}
+		if bl < 0 || bl > maxBulkLen {
+			return nil, 0, 0, protocolError("invalid bulk length")
+		}
 
-func parseArray(s string) ([]any, error) {
-	s = s[1:]
-	elems := strings.SplitN(s, "\r\n", 2)[1]
-
-	c := 0
-	res := make([]any, 0)
-	for c < len(elems) {
-		r := []byte(elems[c:])
-		if cc := eatBulkString(r, 0); cc != 0 {
-			v, _ := parseBulkString(elems[c : c+cc])
-			res = append(res, v)
-			c += cc
-			continue
+		// The payload is read by length, so it may contain any byte, CRLF included.
+		start := pos + hs
+		end := start + bl + 2
+		if end > len(buf) {
+			return nil, 0, end - len(buf), nil
+		}
+		if buf[end-2] != '\r' || buf[end-1] != '\n' {
+			return nil, 0, 0, protocolError("expected CRLF after bulk string")
 		}
+		args = append(args, buf[start:start+bl:start+bl])
+		pos = end
+	}
+	return args, pos, 0, nil
+}
 
-		return nil, fmt.Errorf("unknown element type in array: %#v", elems)
+var (
+	errorLengthInvalid = fmt.Errorf("invalid length")
+	errorLengthTooBig  = fmt.Errorf("length header too big")
+)
+
+// parseLength parses the "<type><length>\r\n" header at the start of buf.
+// It returns the length and the size of the header, which is 0 when the
+// header is not complete yet.
+func parseLength(buf []byte) (l int, size int, err error) {
+	i := bytes.Index(buf, []byte("\r\n"))
+	if i < 0 {
+		if len(buf) > maxInlineLen {
+			return 0, 0, errorLengthTooBig
+		}
+		return 0, 0, nil
 	}
 
-	return res, nil
+	digits := buf[1:i]
+	neg := len(digits) > 0 && digits[0] == '-'
+	if neg {
+		digits = digits[1:]
+	}
+	// Longer values could overflow, and are way past every limit anyway.
+	if len(digits) == 0 || len(digits) > 18 {
+		return 0, 0, errorLengthInvalid
+	}
+	for _, d := range digits {
+		if d < '0' || d > '9' {
+			return 0, 0, errorLengthInvalid
+		}
+		l = l*10 + int(d-'0')
+	}
+	if neg {
+		l = -l
+	}
+	return l, i + 2, nil
 }
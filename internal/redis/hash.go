@@ -0,0 +1,590 @@
+package redis
+
+import (
+	"math"
+	"math/big"
+	"math/bits"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Avik32223/redis-server/internal/rediserr"
+)
+
+var hashCommands = []*commandSpec{
+	{
+		name: "hset", run: hset, arity: -4,
+		flags:    []string{flagWrite, flagDenyOOM, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "UPDATE"},
+		categories: []string{"@write", "@hash", "@fast"},
+		group:      "hash", since: "2.0.0", complexity: "O(1) for each field/value pair added, so O(N) to add N field/value pairs when the command is called with multiple field/value pairs.",
+		summary: "Creates or modifies the value of a field in a hash.",
+	},
+	{
+		name: "hsetnx", run: hsetnx, arity: 4,
+		flags:    []string{flagWrite, flagDenyOOM, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "INSERT"},
+		categories: []string{"@write", "@hash", "@fast"},
+		group:      "hash", since: "2.0.0", complexity: "O(1)",
+		summary: "Sets the value of a field in a hash only when the field doesn't exist.",
+	},
+	{
+		name: "hget", run: hget, arity: 3,
+		flags:    []string{flagReadonly, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RO", "ACCESS"},
+		categories: []string{"@read", "@hash", "@fast"},
+		group:      "hash", since: "2.0.0", complexity: "O(1)",
+		summary: "Returns the value of a field in a hash.",
+	},
+	{
+		name: "hmget", run: hmget, arity: -3,
+		flags:    []string{flagReadonly, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RO", "ACCESS"},
+		categories: []string{"@read", "@hash", "@fast"},
+		group:      "hash", since: "2.0.0", complexity: "O(N) where N is the number of fields being requested.",
+		summary: "Returns the values of all fields in a hash.",
+	},
+	{
+		name: "hgetall", run: hgetall, arity: 2,
+		flags:    []string{flagReadonly},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RO", "ACCESS"},
+		categories: []string{"@read", "@hash", "@slow"},
+		group:      "hash", since: "2.0.0", complexity: "O(N) where N is the size of the hash.",
+		summary: "Returns all fields and values in a hash.",
+	},
+	{
+		name: "hdel", run: hdel, arity: -3,
+		flags:    []string{flagWrite, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "DELETE"},
+		categories: []string{"@write", "@hash", "@fast"},
+		group:      "hash", since: "2.0.0", complexity: "O(N) where N is the number of fields to be removed.",
+		summary: "Deletes one or more fields and their values from a hash. Deletes the hash if no fields remain.",
+	},
+	{
+		name: "hexists", run: hexists, arity: 3,
+		flags:    []string{flagReadonly, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RO"},
+		categories: []string{"@read", "@hash", "@fast"},
+		group:      "hash", since: "2.0.0", complexity: "O(1)",
+		summary: "Determines whether a field exists in a hash.",
+	},
+	{
+		name: "hlen", run: hlen, arity: 2,
+		flags:    []string{flagReadonly, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RO"},
+		categories: []string{"@read", "@hash", "@fast"},
+		group:      "hash", since: "2.0.0", complexity: "O(1)",
+		summary: "Returns the number of fields in a hash.",
+	},
+	{
+		name: "hkeys", run: hkeys, arity: 2,
+		flags:    []string{flagReadonly},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RO"},
+		categories: []string{"@read", "@hash", "@slow"},
+		group:      "hash", since: "2.0.0", complexity: "O(N) where N is the size of the hash.",
+		summary: "Returns all fields in a hash.",
+	},
+	{
+		name: "hvals", run: hvals, arity: 2,
+		flags:    []string{flagReadonly},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RO", "ACCESS"},
+		categories: []string{"@read", "@hash", "@slow"},
+		group:      "hash", since: "2.0.0", complexity: "O(N) where N is the size of the hash.",
+		summary: "Returns all values in a hash.",
+	},
+	{
+		name: "hstrlen", run: hstrlen, arity: 3,
+		flags:    []string{flagReadonly, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RO"},
+		categories: []string{"@read", "@hash", "@fast"},
+		group:      "hash", since: "3.2.0", complexity: "O(1)",
+		summary: "Returns the length of the value of a field.",
+	},
+	{
+		name: "hincrby", run: hincrby, arity: 4,
+		flags:    []string{flagWrite, flagDenyOOM, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "UPDATE"},
+		categories: []string{"@write", "@hash", "@fast"},
+		group:      "hash", since: "2.0.0", complexity: "O(1)",
+		summary: "Increments the integer value of a field in a hash by a number. Uses 0 as initial value if the field doesn't exist.",
+	},
+	{
+		name: "hincrbyfloat", run: hincrbyfloat, arity: 4,
+		flags:    []string{flagWrite, flagDenyOOM, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "UPDATE"},
+		categories: []string{"@write", "@hash", "@fast"},
+		group:      "hash", since: "2.6.0", complexity: "O(1)",
+		summary: "Increments the floating point value of a field by a number. Uses 0 as initial value if the field doesn't exist.",
+	},
+	{
+		name: "hrandfield", run: hrandfield, arity: -2,
+		flags:    []string{flagReadonly},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RO", "ACCESS"},
+		categories: []string{"@read", "@hash", "@slow"},
+		group:      "hash", since: "6.2.0", complexity: "O(N) where N is the number of fields returned",
+		summary: "Returns one or more random fields from a hash.",
+	},
+	{
+		name: "hscan", run: hscan, arity: -3,
+		flags:    []string{flagReadonly},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RO", "ACCESS"},
+		categories: []string{"@read", "@hash", "@slow"},
+		group:      "hash", since: "2.8.0", complexity: "O(1) for every call. O(N) for a complete iteration, including enough command calls for the cursor to return back to 0. N is the number of elements inside the collection.",
+		summary: "Iterates over fields and values of a hash.",
+	},
+}
+
+// hash is the value of hash keys, mapping fields to their values.
+// Fields are also laid out in buckets by their hash, for HSCAN to walk them:
+// a power of two buckets, from an eighth as many fields to as many.
+type hash struct {
+	fields  map[string]string
+	buckets [][]string
+}
+
+// minHashBuckets is the fewest buckets a hash is laid out in.
+const minHashBuckets = 4
+
+func newHash() *hash {
+	return &hash{fields: map[string]string{}, buckets: make([][]string, minHashBuckets)}
+}
+
+// lookupHash returns the hash stored at key, or nil if key does not exist.
+func lookupHash(s State, key string) (*hash, error) {
+	v, err := lookup(s, key)
+	if err != nil {
+		return nil, nil
+	}
+	h, ok := v.(*hash)
+	if !ok {
+		return nil, rediserr.WrongType
+	}
+	return h, nil
+}
+
+// Len returns the number of fields of h. Like get, it reads a nil hash,
+// that of a missing key, as an empty one.
+func (h *hash) Len() int {
+	if h == nil {
+		return 0
+	}
+	return len(h.fields)
+}
+
+// get returns the value of field f, and whether h has it.
+func (h *hash) get(f string) (string, bool) {
+	if h == nil {
+		return "", false
+	}
+	v, ok := h.fields[f]
+	return v, ok
+}
+
+func (h *hash) bucket(f string) uint64 {
+	return xxh3([]byte(f)) & uint64(len(h.buckets)-1)
+}
+
+// set sets field f to v, and reports whether f was added.
+func (h *hash) set(f, v string) bool {
+	_, ok := h.fields[f]
+	h.fields[f] = v
+	if ok {
+		return false
+	}
+	if len(h.fields) > len(h.buckets) {
+		h.rehash(len(h.buckets) * 2)
+	} else {
+		b := h.bucket(f)
+		h.buckets[b] = append(h.buckets[b], f)
+	}
+	return true
+}
+
+// del deletes field f, and reports whether it was there.
+func (h *hash) del(f string) bool {
+	if _, ok := h.fields[f]; !ok {
+		return false
+	}
+	delete(h.fields, f)
+	if n := len(h.buckets); n > minHashBuckets && len(h.fields) < n/8 {
+		h.rehash(n / 2)
+		return true
+	}
+	b := h.bucket(f)
+	i := slices.Index(h.buckets[b], f)
+	h.buckets[b] = slices.Delete(h.buckets[b], i, i+1)
+	return true
+}
+
+// rehash lays the fields of h out in n buckets.
+func (h *hash) rehash(n int) {
+	h.buckets = make([][]string, n)
+	for f := range h.fields {
+		b := h.bucket(f)
+		h.buckets[b] = append(h.buckets[b], f)
+	}
+}
+
+// scan returns the fields of the buckets from cursor on, until about count
+// of them are found or ten times as many buckets are walked, along with the
+// cursor to scan the next ones from, 0 once every bucket is walked.
+//
+// As redis does, cursors count with their bits reversed, so that bucket b
+// of n is walked before bucket b+n/2: once h is rehashed into twice as many
+// buckets or half as many, the buckets a cursor leads to hold the fields
+// not scanned yet, and possibly some that were, but never skip any. A field
+// there from the start to the end of a scan is thus returned at least once.
+func (h *hash) scan(cursor uint64, count int) ([]string, uint64) {
+	mask := uint64(len(h.buckets) - 1)
+	var fields []string
+	for walked := 0; ; walked++ {
+		fields = append(fields, h.buckets[cursor&mask]...)
+		cursor = bits.Reverse64(bits.Reverse64(cursor|^mask) + 1)
+		if cursor == 0 || len(fields) >= count || walked >= count*10 {
+			return fields, cursor
+		}
+	}
+}
+
+// hset sets fields to values, and replies with the number of fields added.
+func hset(c *Client, ca ...any) (Reply, error) {
+	if len(ca)%2 == 0 {
+		return nil, rediserr.WrongArity("hset")
+	}
+	s := c.db()
+	key := ca[0].(string)
+	h, err := lookupHash(s, key)
+	if err != nil {
+		return nil, err
+	}
+	if h == nil {
+		h = newHash()
+	}
+	added := 0
+	for i := 1; i < len(ca); i += 2 {
+		if h.set(ca[i].(string), ca[i+1].(string)) {
+			added++
+		}
+	}
+	storeContainer(s, key, h)
+	return IntegerReply(added), nil
+}
+
+func hsetnx(c *Client, ca ...any) (Reply, error) {
+	s := c.db()
+	key, f := ca[0].(string), ca[1].(string)
+	h, err := lookupHash(s, key)
+	if err != nil {
+		return nil, err
+	}
+	if h == nil {
+		h = newHash()
+	}
+	if _, ok := h.fields[f]; ok {
+		return IntegerReply(0), nil
+	}
+	h.set(f, ca[2].(string))
+	storeContainer(s, key, h)
+	return IntegerReply(1), nil
+}
+
+func hget(c *Client, ca ...any) (Reply, error) {
+	h, err := lookupHash(c.db(), ca[0].(string))
+	if err != nil {
+		return nil, err
+	}
+	v, ok := h.get(ca[1].(string))
+	if !ok {
+		return NullBulkReply{}, nil
+	}
+	return BulkReply(v), nil
+}
+
+func hmget(c *Client, ca ...any) (Reply, error) {
+	h, err := lookupHash(c.db(), ca[0].(string))
+	if err != nil {
+		return nil, err
+	}
+	res := make(ArrayReply, 0, len(ca)-1)
+	for _, f := range ca[1:] {
+		if v, ok := h.get(f.(string)); ok {
+			res = append(res, BulkReply(v))
+		} else {
+			res = append(res, NullBulkReply{})
+		}
+	}
+	return res, nil
+}
+
+func hgetall(c *Client, ca ...any) (Reply, error) {
+	h, err := lookupHash(c.db(), ca[0].(string))
+	if h == nil || err != nil {
+		return emptyArray, err
+	}
+	res := make(MapReply, 0, len(h.fields))
+	for f, v := range h.fields {
+		res = append(res, KeyValue{Key: BulkReply(f), Value: BulkReply(v)})
+	}
+	return res, nil
+}
+
+func hdel(c *Client, ca ...any) (Reply, error) {
+	s := c.db()
+	key := ca[0].(string)
+	h, err := lookupHash(s, key)
+	if h == nil || err != nil {
+		return IntegerReply(0), err
+	}
+	removed := 0
+	for _, f := range ca[1:] {
+		if h.del(f.(string)) {
+			removed++
+		}
+	}
+	storeContainer(s, key, h)
+	return IntegerReply(removed), nil
+}
+
+func hexists(c *Client, ca ...any) (Reply, error) {
+	h, err := lookupHash(c.db(), ca[0].(string))
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := h.get(ca[1].(string)); ok {
+		return IntegerReply(1), nil
+	}
+	return IntegerReply(0), nil
+}
+
+func hlen(c *Client, ca ...any) (Reply, error) {
+	h, err := lookupHash(c.db(), ca[0].(string))
+	if err != nil {
+		return nil, err
+	}
+	return IntegerReply(h.Len()), nil
+}
+
+func hkeys(c *Client, ca ...any) (Reply, error) {
+	h, err := lookupHash(c.db(), ca[0].(string))
+	if h == nil || err != nil {
+		return emptyArray, err
+	}
+	res := make(ArrayReply, 0, len(h.fields))
+	for f := range h.fields {
+		res = append(res, BulkReply(f))
+	}
+	return res, nil
+}
+
+func hvals(c *Client, ca ...any) (Reply, error) {
+	h, err := lookupHash(c.db(), ca[0].(string))
+	if h == nil || err != nil {
+		return emptyArray, err
+	}
+	res := make(ArrayReply, 0, len(h.fields))
+	for _, v := range h.fields {
+		res = append(res, BulkReply(v))
+	}
+	return res, nil
+}
+
+func hstrlen(c *Client, ca ...any) (Reply, error) {
+	h, err := lookupHash(c.db(), ca[0].(string))
+	if err != nil {
+		return nil, err
+	}
+	v, _ := h.get(ca[1].(string))
+	return IntegerReply(len(v)), nil
+}
+
+// hincrby adds an increment to the integer value of a field,
+// 0 if the field does not exist.
+func hincrby(c *Client, ca ...any) (Reply, error) {
+	s := c.db()
+	key, f := ca[0].(string), ca[1].(string)
+	by, err := parseInt(ca[2].(string))
+	if err != nil {
+		return nil, err
+	}
+	h, err := lookupHash(s, key)
+	if err != nil {
+		return nil, err
+	}
+	if h == nil {
+		h = newHash()
+	}
+	var i int64
+	if v, ok := h.fields[f]; ok {
+		if i, err = parseInt(v); err != nil {
+			return nil, rediserr.New(rediserr.ERR, "hash value is not an integer")
+		}
+	}
+	if (by < 0 && i < 0 && by < math.MinInt64-i) || (by > 0 && i > 0 && by > math.MaxInt64-i) {
+		return nil, rediserr.Overflow
+	}
+	i += by
+	h.set(f, strconv.FormatInt(i, 10))
+	storeContainer(s, key, h)
+	return IntegerReply(i), nil
+}
+
+// hincrbyfloat adds an increment to the float value of a field, 0 if the
+// field does not exist, computed as INCRBYFLOAT computes it.
+func hincrbyfloat(c *Client, ca ...any) (Reply, error) {
+	s := c.db()
+	key, f := ca[0].(string), ca[1].(string)
+	by, err := parseLongDouble(ca[2].(string))
+	if err != nil {
+		return nil, err
+	}
+	if by.IsInf() {
+		return nil, rediserr.New(rediserr.ERR, "value is NaN or Infinity")
+	}
+	h, err := lookupHash(s, key)
+	if err != nil {
+		return nil, err
+	}
+	if h == nil {
+		h = newHash()
+	}
+	n := new(big.Float).SetPrec(longDoublePrec)
+	if v, ok := h.fields[f]; ok {
+		if n, err = parseLongDouble(v); err != nil {
+			return nil, rediserr.New(rediserr.ERR, "hash value is not a float")
+		}
+	}
+	if n.IsInf() || n.Add(n, by).MantExp(nil) > longDoubleMaxExp {
+		return nil, rediserr.New(rediserr.ERR, "increment would produce NaN or Infinity")
+	}
+	d := formatLongDouble(n)
+	h.set(f, d)
+	storeContainer(s, key, h)
+	return BulkReply(d), nil
+}
+
+// hrandfield replies with a random field or, with a count, with an array
+// of up to count distinct fields, or of exactly -count fields that may
+// repeat if count is negative. WITHVALUES replies with their values too,
+// in pairs for RESP3 clients.
+//
+// Unlike redis, which streams its replies, this server builds a reply whole
+// before sending it. So that a negative count cannot make it allocate an
+// unbounded reply, -count may be no more than maxMultibulkLen, the most
+// arguments a request may carry, or half as many pairs with WITHVALUES.
+func hrandfield(c *Client, ca ...any) (Reply, error) {
+	if len(ca) == 1 {
+		h, err := lookupHash(c.db(), ca[0].(string))
+		if h == nil || err != nil {
+			return NullBulkReply{}, err
+		}
+		for f := range h.fields {
+			return BulkReply(f), nil
+		}
+		return NullBulkReply{}, nil
+	}
+
+	count, err := parseRange(ca[1].(string), -math.MaxInt64, math.MaxInt64, "")
+	if err != nil {
+		return nil, err
+	}
+	withValues := len(ca) == 3
+	if len(ca) > 3 || (withValues && strings.ToUpper(ca[2].(string)) != "WITHVALUES") {
+		return nil, rediserr.Syntax
+	}
+	if withValues && (count < -math.MaxInt64/2 || count > math.MaxInt64/2) {
+		return nil, rediserr.New(rediserr.ERR, "value is out of range")
+	}
+	h, err := lookupHash(c.db(), ca[0].(string))
+	if h == nil || err != nil {
+		return emptyArray, err
+	}
+	if elements := -count; elements > maxMultibulkLen || (withValues && elements > maxMultibulkLen/2) {
+		return nil, rediserr.New(rediserr.ERR, "value is out of range")
+	}
+
+	fields := make([]string, 0, len(h.fields))
+	for f := range h.fields {
+		fields = append(fields, f)
+	}
+	res := ArrayReply{}
+	add := func(f string) {
+		switch {
+		case !withValues:
+			res = append(res, BulkReply(f))
+		case c.opts.resp3():
+			res = append(res, ArrayReply{BulkReply(f), BulkReply(h.fields[f])})
+		default:
+			res = append(res, BulkReply(f), BulkReply(h.fields[f]))
+		}
+	}
+	switch {
+	case count == 0:
+	case count < 0:
+		for i := int64(0); i < -count; i++ {
+			add(fields[rand.Intn(len(fields))])
+		}
+	default:
+		// The first count fields of a partial shuffle.
+		n := int(min(count, int64(len(fields))))
+		for i := 0; i < n; i++ {
+			j := i + rand.Intn(len(fields)-i)
+			fields[i], fields[j] = fields[j], fields[i]
+			add(fields[i])
+		}
+	}
+	return res, nil
+}
+
+// hscan replies with a page of about COUNT fields of a hash, 10 by default,
+// along with their values unless NOVALUES is given, and with the cursor to
+// scan the next page from, 0 once the whole hash is scanned. Only fields
+// matching the pattern given with MATCH are replied with.
+func hscan(c *Client, ca ...any) (Reply, error) {
+	cursor, err := strconv.ParseUint(ca[1].(string), 10, 64)
+	if err != nil {
+		return nil, rediserr.New(rediserr.ERR, "invalid cursor")
+	}
+	h, err := lookupHash(c.db(), ca[0].(string))
+	if err != nil {
+		return nil, err
+	}
+	if h == nil {
+		return ArrayReply{BulkReply("0"), emptyArray}, nil
+	}
+
+	count, pattern, noValues := int64(10), "", false
+	for i := 2; i < len(ca); i++ {
+		opt := strings.ToUpper(ca[i].(string))
+		switch {
+		case opt == "NOVALUES":
+			noValues = true
+		case opt == "COUNT" && i+1 < len(ca):
+			i++
+			if count, err = parseInt(ca[i].(string)); err != nil {
+				return nil, err
+			}
+			if count < 1 {
+				return nil, rediserr.Syntax
+			}
+		case opt == "MATCH" && i+1 < len(ca):
+			i++
+			pattern = ca[i].(string)
+		default:
+			return nil, rediserr.Syntax
+		}
+	}
+
+	fields, next := h.scan(cursor, int(min(count, math.MaxInt32)))
+	res := ArrayReply{}
+	for _, f := range fields {
+		if pattern != "" && !stringMatch(pattern, f) {
+			continue
+		}
+		res = append(res, BulkReply(f))
+		if !noValues {
+			res = append(res, BulkReply(h.fields[f]))
+		}
+	}
+	return ArrayReply{BulkReply(strconv.FormatUint(next, 10)), res}, nil
+}
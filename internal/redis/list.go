@@ -0,0 +1,736 @@
+package redis
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Avik32223/redis-server/internal/rediserr"
+	"github.com/Avik32223/redis-server/pkg/lists"
+)
+
+var listCommands = []*commandSpec{
+	{
+		name: "lrange", run: lrange, arity: 4,
+		flags:    []string{flagReadonly},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RO", "ACCESS"},
+		categories: []string{"@read", "@list", "@slow"},
+		group:      "list", since: "1.0.0", complexity: "O(S+N) where S is the distance of start offset from HEAD for small lists, from nearest end (HEAD or TAIL) for large lists; and N is the number of elements in the specified range.",
+		summary: "Returns a range of elements from a list.",
+	},
+	{
+		name: "llen", run: llen, arity: 2,
+		flags:    []string{flagReadonly, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RO"},
+		categories: []string{"@read", "@list", "@fast"},
+		group:      "list", since: "1.0.0", complexity: "O(1)",
+		summary: "Returns the length of a list.",
+	},
+	{
+		name: "lindex", run: lindex, arity: 3,
+		flags:    []string{flagReadonly},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RO", "ACCESS"},
+		categories: []string{"@read", "@list", "@slow"},
+		group:      "list", since: "1.0.0", complexity: "O(N) where N is the number of elements to traverse to get to the element at index. This makes asking for the first or the last element of the list O(1).",
+		summary: "Returns an element from a list by its index.",
+	},
+	{
+		name: "lset", run: lset, arity: 4,
+		flags:    []string{flagWrite, flagDenyOOM},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "UPDATE"},
+		categories: []string{"@write", "@list", "@slow"},
+		group:      "list", since: "1.0.0", complexity: "O(N) where N is the length of the list. Setting either the first or the last element of the list is O(1).",
+		summary: "Sets the value of an element in a list by its index.",
+	},
+	{
+		name: "linsert", run: linsert, arity: 5,
+		flags:    []string{flagWrite, flagDenyOOM},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "INSERT"},
+		categories: []string{"@write", "@list", "@slow"},
+		group:      "list", since: "2.2.0", complexity: "O(N) where N is the number of elements to traverse before seeing the value pivot. This means that inserting somewhere on the left end on the list (head) can be considered O(1) and inserting somewhere on the right end (tail) is O(N).",
+		summary: "Inserts an element before or after another element in a list.",
+	},
+	{
+		name: "lrem", run: lrem, arity: 4,
+		flags:    []string{flagWrite},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "DELETE"},
+		categories: []string{"@write", "@list", "@slow"},
+		group:      "list", since: "1.0.0", complexity: "O(N+M) where N is the length of the list and M is the number of elements removed.",
+		summary: "Removes elements from a list. Deletes the list if the last element was removed.",
+	},
+	{
+		name: "ltrim", run: ltrim, arity: 4,
+		flags:    []string{flagWrite},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "DELETE"},
+		categories: []string{"@write", "@list", "@slow"},
+		group:      "list", since: "1.0.0", complexity: "O(N) where N is the number of elements to be removed by the operation.",
+		summary: "Removes elements from both ends a list. Deletes the list if all elements were trimmed.",
+	},
+	{
+		name: "lpos", run: lpos, arity: -3,
+		flags:    []string{flagReadonly},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RO"},
+		categories: []string{"@read", "@list", "@slow"},
+		group:      "list", since: "6.0.6", complexity: "O(N) where N is the number of elements in the list, for the average case. When searching for elements near the head or the tail of the list, or when the MAXLEN option is provided, the command may run in constant time.",
+		summary: "Returns the index of matching elements in a list.",
+	},
+	{
+		name: "lpop", run: lpop, arity: -2,
+		flags:    []string{flagWrite, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "DELETE"},
+		categories: []string{"@write", "@list", "@fast"},
+		group:      "list", since: "1.0.0", complexity: "O(N) where N is the number of elements returned",
+		summary: "Returns the first elements in a list after removing it. Deletes the list if the last element was popped.",
+	},
+	{
+		name: "rpop", run: rpop, arity: -2,
+		flags:    []string{flagWrite, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "DELETE"},
+		categories: []string{"@write", "@list", "@fast"},
+		group:      "list", since: "1.0.0", complexity: "O(N) where N is the number of elements returned",
+		summary: "Returns and removes the last elements of the list. Deletes the list if the last element was popped.",
+	},
+	{
+		name: "lmpop", run: lmpop, arity: -4,
+		flags:       []string{flagWrite, flagMovableKeys},
+		keyNumIndex: 1, keyFlags: []string{"RW", "ACCESS", "DELETE"},
+		categories: []string{"@write", "@list", "@slow"},
+		group:      "list", since: "7.0.0", complexity: "O(N+M) where N is the number of provided keys and M is the number of elements returned.",
+		summary: "Returns multiple elements from a list after removing them. Deletes the list if the last element was popped.",
+	},
+	{
+		name: "lmove", run: lmove, arity: 5,
+		flags:    []string{flagWrite, flagDenyOOM},
+		firstKey: 1, lastKey: 2, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "DELETE", "INSERT"},
+		categories: []string{"@write", "@list", "@slow"},
+		group:      "list", since: "6.2.0", complexity: "O(1)",
+		summary: "Returns an element after popping it from one list and pushing it to another. Deletes the list if the last element was moved.",
+	},
+	{
+		name: "rpoplpush", run: rpoplpush, arity: 3,
+		flags:    []string{flagWrite, flagDenyOOM},
+		firstKey: 1, lastKey: 2, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "DELETE", "INSERT"},
+		categories: []string{"@write", "@list", "@slow"},
+		group:      "list", since: "1.2.0", complexity: "O(1)",
+		summary: "Returns the last element of a list after removing and pushing it to another list. Deletes the list if the last element was popped.",
+	},
+	{
+		name: "lpushx", run: lpushx, arity: -3,
+		flags:    []string{flagWrite, flagDenyOOM, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "INSERT"},
+		categories: []string{"@write", "@list", "@fast"},
+		group:      "list", since: "2.2.0", complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments.",
+		summary: "Prepends one or more elements to a list only when the list exists.",
+	},
+	{
+		name: "rpushx", run: rpushx, arity: -3,
+		flags:    []string{flagWrite, flagDenyOOM, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "INSERT"},
+		categories: []string{"@write", "@list", "@fast"},
+		group:      "list", since: "2.2.0", complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments.",
+		summary: "Appends an element to a list only when the list exists.",
+	},
+	{
+		name: "blpop", run: blpop, arity: -3,
+		flags:    []string{flagWrite, flagBlocking},
+		firstKey: 1, lastKey: -2, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "DELETE"},
+		categories: []string{"@write", "@list", "@slow", "@blocking"},
+		group:      "list", since: "2.0.0", complexity: "O(N) where N is the number of provided keys.",
+		summary: "Removes and returns the first element in a list. Blocks until an element is available otherwise. Deletes the list if the last element was popped.",
+	},
+	{
+		name: "brpop", run: brpop, arity: -3,
+		flags:    []string{flagWrite, flagBlocking},
+		firstKey: 1, lastKey: -2, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "DELETE"},
+		categories: []string{"@write", "@list", "@slow", "@blocking"},
+		group:      "list", since: "2.0.0", complexity: "O(N) where N is the number of provided keys.",
+		summary: "Removes and returns the last element in a list. Blocks until an element is available otherwise. Deletes the list if the last element was popped.",
+	},
+	{
+		name: "blmove", run: blmove, arity: 6,
+		flags:    []string{flagWrite, flagDenyOOM, flagBlocking},
+		firstKey: 1, lastKey: 2, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "DELETE", "INSERT"},
+		categories: []string{"@write", "@list", "@slow", "@blocking"},
+		group:      "list", since: "6.2.0", complexity: "O(1)",
+		summary: "Pops an element from a list, pushes it to another list and returns it. Blocks until an element is available otherwise. Deletes the list if the last element was moved.",
+	},
+	{
+		name: "blmpop", run: blmpop, arity: -5,
+		flags:       []string{flagWrite, flagBlocking, flagMovableKeys},
+		keyNumIndex: 2, keyFlags: []string{"RW", "ACCESS", "DELETE"},
+		categories: []string{"@write", "@list", "@slow", "@blocking"},
+		group:      "list", since: "7.0.0", complexity: "O(N+M) where N is the number of provided keys and M is the number of elements returned.",
+		summary: "Pops the first element from one of multiple lists. Blocks until an element is available otherwise. Deletes the list if the last element was popped.",
+	},
+}
+
+// The ends of a list elements are pushed to and popped from.
+const (
+	listLeft  = "LEFT"
+	listRight = "RIGHT"
+)
+
+// parseListEnd parses the LEFT or RIGHT argument of LMOVE and LMPOP.
+func parseListEnd(s string) (string, error) {
+	end := strings.ToUpper(s)
+	if end != listLeft && end != listRight {
+		return "", rediserr.Syntax
+	}
+	return end, nil
+}
+
+// lookupList returns the list stored at key, or nil if key does not exist.
+func lookupList(s State, key string) (*lists.List, error) {
+	v, err := lookup(s, key)
+	if err != nil {
+		return nil, nil
+	}
+	l, ok := v.(*lists.List)
+	if !ok {
+		return nil, rediserr.WrongType
+	}
+	return l, nil
+}
+
+// listRange converts the start and stop indexes of LRANGE and LTRIM to the
+// indexes of the first and last elements they include in a list of l
+// elements. Negative indexes count from the end of the list. The range is
+// empty if start > stop.
+func listRange(start, stop int64, l int) (int, int) {
+	n := int64(l)
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	start = max(start, 0)
+	stop = min(stop, n-1)
+	if start > stop {
+		return 1, 0
+	}
+	return int(start), int(stop)
+}
+
+func lrange(c *Client, ca ...any) (Reply, error) {
+	start, err := parseInt(ca[1].(string))
+	if err != nil {
+		return nil, err
+	}
+	stop, err := parseInt(ca[2].(string))
+	if err != nil {
+		return nil, err
+	}
+	l, err := lookupList(c.db(), ca[0].(string))
+	if l == nil || err != nil {
+		return emptyArray, err
+	}
+
+	first, last := listRange(start, stop, l.Len())
+	res := ArrayReply{}
+	for it := l.Iterator(first, false); first <= last && it.Next(); first++ {
+		res = append(res, BulkReply(it.Value()))
+	}
+	return res, nil
+}
+
+func llen(c *Client, ca ...any) (Reply, error) {
+	l, err := lookupList(c.db(), ca[0].(string))
+	if l == nil || err != nil {
+		return IntegerReply(0), err
+	}
+	return IntegerReply(l.Len()), nil
+}
+
+func lindex(c *Client, ca ...any) (Reply, error) {
+	l, err := lookupList(c.db(), ca[0].(string))
+	if l == nil || err != nil {
+		return NullBulkReply{}, err
+	}
+	i, err := parseInt(ca[1].(string))
+	if err != nil {
+		return nil, err
+	}
+	if i < -int64(l.Len()) || i >= int64(l.Len()) {
+		return NullBulkReply{}, nil
+	}
+	v, _ := l.Index(int(i))
+	return BulkReply(v), nil
+}
+
+func lset(c *Client, ca ...any) (Reply, error) {
+	s := c.db()
+	key := ca[0].(string)
+	l, err := lookupList(s, key)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil {
+		return nil, rediserr.NoSuchKey
+	}
+	i, err := parseInt(ca[1].(string))
+	if err != nil {
+		return nil, err
+	}
+	if i < -int64(l.Len()) || i >= int64(l.Len()) {
+		return nil, rediserr.OutOfRange
+	}
+	l.Set(int(i), ca[2].(string))
+	return okReply, nil
+}
+
+// linsert inserts an element before or after the first occurrence of pivot,
+// and replies with the new length of the list, or -1 if pivot is missing.
+func linsert(c *Client, ca ...any) (Reply, error) {
+	s := c.db()
+	key, where, pivot := ca[0].(string), strings.ToUpper(ca[1].(string)), ca[2].(string)
+	if where != "BEFORE" && where != "AFTER" {
+		return nil, rediserr.Syntax
+	}
+	l, err := lookupList(s, key)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil {
+		return IntegerReply(0), nil
+	}
+
+	for it := l.Iterator(0, false); it.Next(); {
+		if it.Value() != pivot {
+			continue
+		}
+		i := it.Index()
+		if where == "AFTER" {
+			i++
+		}
+		l.Insert(i, ca[3].(string))
+		return IntegerReply(l.Len()), nil
+	}
+	return IntegerReply(-1), nil
+}
+
+// lrem removes the first count occurrences of an element, the last ones if
+// count is negative, or all of them if it is 0.
+func lrem(c *Client, ca ...any) (Reply, error) {
+	s := c.db()
+	key, element := ca[0].(string), ca[2].(string)
+	count, err := parseInt(ca[1].(string))
+	if err != nil {
+		return nil, err
+	}
+	l, err := lookupList(s, key)
+	if l == nil || err != nil {
+		return IntegerReply(0), err
+	}
+
+	removed := int64(0)
+	it := l.Iterator(0, false)
+	if count < 0 {
+		it = l.Iterator(-1, true)
+		count = -count
+	}
+	for (count == 0 || removed < count) && it.Next() {
+		if it.Value() == element {
+			it.Remove()
+			removed++
+		}
+	}
+	if removed > 0 {
+		storeContainer(s, key, l)
+	}
+	return IntegerReply(removed), nil
+}
+
+// ltrim trims a list to the range of elements from start to stop.
+func ltrim(c *Client, ca ...any) (Reply, error) {
+	s := c.db()
+	key := ca[0].(string)
+	start, err := parseInt(ca[1].(string))
+	if err != nil {
+		return nil, err
+	}
+	stop, err := parseInt(ca[2].(string))
+	if err != nil {
+		return nil, err
+	}
+	l, err := lookupList(s, key)
+	if l == nil || err != nil {
+		return okReply, err
+	}
+
+	first, last := listRange(start, stop, l.Len())
+	if first > last {
+		first, last = l.Len(), l.Len()-1
+	}
+	l.DeleteRange(last+1, l.Len()-last-1)
+	l.DeleteRange(0, first)
+	storeContainer(s, key, l)
+	return okReply, nil
+}
+
+// lpos replies with the index of the first occurrence of an element, or of
+// the one of rank RANK, counting from the end of the list if it is negative.
+// With COUNT, it replies with the indexes of up to COUNT occurrences from
+// there, all of them if it is 0. With MAXLEN, only the first MAXLEN
+// elements are compared.
+func lpos(c *Client, ca ...any) (Reply, error) {
+	element := ca[1].(string)
+	rank, count, maxLen := int64(1), int64(-1), int64(0)
+	for i := 2; i < len(ca); i++ {
+		opt := strings.ToUpper(ca[i].(string))
+		if i+1 >= len(ca) {
+			return nil, rediserr.Syntax
+		}
+		i++
+		var err error
+		switch opt {
+		case "RANK":
+			if rank, err = parseRange(ca[i].(string), -math.MaxInt64, math.MaxInt64, ""); err != nil {
+				return nil, err
+			}
+			if rank == 0 {
+				return nil, rediserr.New(rediserr.ERR, "RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list")
+			}
+		case "COUNT":
+			if count, err = parseRange(ca[i].(string), 0, math.MaxInt64, "COUNT can't be negative"); err != nil {
+				return nil, err
+			}
+		case "MAXLEN":
+			if maxLen, err = parseRange(ca[i].(string), 0, math.MaxInt64, "MAXLEN can't be negative"); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, rediserr.Syntax
+		}
+	}
+
+	l, err := lookupList(c.db(), ca[0].(string))
+	if err != nil {
+		return nil, err
+	}
+	if l == nil {
+		if count != -1 {
+			return emptyArray, nil
+		}
+		return NullBulkReply{}, nil
+	}
+
+	it := l.Iterator(0, false)
+	if rank < 0 {
+		rank = -rank
+		it = l.Iterator(-1, true)
+	}
+	matches := ArrayReply{}
+	var seen int64
+	for i := int64(0); (maxLen == 0 || i < maxLen) && it.Next(); i++ {
+		if it.Value() != element {
+			continue
+		}
+		if seen++; seen < rank {
+			continue
+		}
+		if count == -1 {
+			return IntegerReply(it.Index()), nil
+		}
+		matches = append(matches, IntegerReply(it.Index()))
+		if count != 0 && int64(len(matches)) >= count {
+			break
+		}
+	}
+	if count == -1 {
+		return NullBulkReply{}, nil
+	}
+	return matches, nil
+}
+
+// listPush pushes v to one end of l.
+func listPush(l *lists.List, end string, v string) {
+	if end == listLeft {
+		l.Prepend(v)
+	} else {
+		l.Append(v)
+	}
+}
+
+// listPop removes up to count elements from one end of l,
+// and returns them in the order they are popped.
+func listPop(l *lists.List, end string, count int64) ArrayReply {
+	res := ArrayReply{}
+	pop := l.PopFront
+	if end == listRight {
+		pop = l.PopBack
+	}
+	for ; count > 0 && l.Len() > 0; count-- {
+		v, _ := pop()
+		res = append(res, BulkReply(v))
+	}
+	return res
+}
+
+func lpop(c *Client, ca ...any) (Reply, error) {
+	return popCommand(c, "lpop", listLeft, ca...)
+}
+
+func rpop(c *Client, ca ...any) (Reply, error) {
+	return popCommand(c, "rpop", listRight, ca...)
+}
+
+// popCommand pops an element from one end of a list or, with a count,
+// replies with an array of up to count elements.
+func popCommand(c *Client, name, end string, ca ...any) (Reply, error) {
+	s := c.db()
+	key := ca[0].(string)
+	if len(ca) > 2 {
+		return nil, rediserr.WrongArity(name)
+	}
+	count := int64(1)
+	if len(ca) == 2 {
+		var err error
+		if count, err = parseRange(ca[1].(string), 0, math.MaxInt64, "value is out of range, must be positive"); err != nil {
+			return nil, err
+		}
+	}
+	l, err := lookupList(s, key)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil {
+		if len(ca) == 2 {
+			return NullArrayReply{}, nil
+		}
+		return NullBulkReply{}, nil
+	}
+
+	popped := listPop(l, end, count)
+	storeContainer(s, key, l)
+	if len(ca) == 2 {
+		return popped, nil
+	}
+	return popped[0], nil
+}
+
+// lmpop pops up to COUNT elements, 1 by default, from the first non-empty
+// list among the keys, and replies with its key and the elements popped.
+func lmpop(c *Client, ca ...any) (Reply, error) {
+	keys, end, count, err := parseMPop(ca)
+	if err != nil {
+		return nil, err
+	}
+	r, err := mpop(c.db(), keys, end, count)
+	if r == nil && err == nil {
+		return NullArrayReply{}, nil
+	}
+	return r, err
+}
+
+// parseMPop parses the arguments of LMPOP: the number of keys, the keys,
+// the end of the lists to pop from, and a COUNT option.
+func parseMPop(ca []any) ([]string, string, int64, error) {
+	n, err := parseRange(ca[0].(string), 1, math.MaxInt64, "numkeys should be greater than 0")
+	if err != nil {
+		return nil, "", 0, err
+	}
+	if n >= int64(len(ca)-1) {
+		return nil, "", 0, rediserr.Syntax
+	}
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = ca[1+i].(string)
+	}
+	end, err := parseListEnd(ca[n+1].(string))
+	if err != nil {
+		return nil, "", 0, err
+	}
+	count := int64(-1)
+	for i := int(n) + 2; i < len(ca); i++ {
+		if count != -1 || strings.ToUpper(ca[i].(string)) != "COUNT" || i+1 >= len(ca) {
+			return nil, "", 0, rediserr.Syntax
+		}
+		i++
+		if count, err = parseRange(ca[i].(string), 1, math.MaxInt64, "count should be greater than 0"); err != nil {
+			return nil, "", 0, err
+		}
+	}
+	return keys, end, max(count, 1), nil
+}
+
+// mpop pops up to count elements from the first non-empty list among keys,
+// and replies nil if there is none.
+func mpop(s State, keys []string, end string, count int64) (Reply, error) {
+	for _, key := range keys {
+		l, err := lookupList(s, key)
+		if err != nil {
+			return nil, err
+		}
+		if l == nil {
+			continue
+		}
+		popped := listPop(l, end, count)
+		storeContainer(s, key, l)
+		return ArrayReply{BulkReply(key), popped}, nil
+	}
+	return nil, nil
+}
+
+func lmove(c *Client, ca ...any) (Reply, error) {
+	from, err := parseListEnd(ca[2].(string))
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseListEnd(ca[3].(string))
+	if err != nil {
+		return nil, err
+	}
+	return listMove(c.db(), ca[0].(string), ca[1].(string), from, to)
+}
+
+func rpoplpush(c *Client, ca ...any) (Reply, error) {
+	return listMove(c.db(), ca[0].(string), ca[1].(string), listRight, listLeft)
+}
+
+// listMove pops an element from one end of the list at src, pushes it to
+// one end of the list at dst, creating it if needed, and replies with it.
+// src and dst may be the same list, to rotate it.
+func listMove(s State, src, dst, from, to string) (Reply, error) {
+	sl, err := lookupList(s, src)
+	if err != nil {
+		return nil, err
+	}
+	if sl == nil {
+		return NullBulkReply{}, nil
+	}
+	dl, err := lookupList(s, dst)
+	if err != nil {
+		return nil, err
+	}
+	if src == dst {
+		dl = sl
+	} else if dl == nil {
+		dl = lists.NewList()
+	}
+
+	v := listPop(sl, from, 1)[0]
+	listPush(dl, to, string(v.(BulkReply)))
+	if src != dst {
+		storeContainer(s, dst, dl)
+	}
+	storeContainer(s, src, sl)
+	return v, nil
+}
+
+func lpushx(c *Client, ca ...any) (Reply, error) {
+	return pushxCommand(c, listLeft, ca...)
+}
+
+func rpushx(c *Client, ca ...any) (Reply, error) {
+	return pushxCommand(c, listRight, ca...)
+}
+
+// pushCommand pushes elements to one end of a list, creating it if needed,
+// and replies with the length of the list.
+func pushCommand(c *Client, end string, ca ...any) (Reply, error) {
+	s := c.db()
+	key := ca[0].(string)
+	l, err := lookupList(s, key)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil {
+		l = lists.NewList()
+	}
+	for _, v := range ca[1:] {
+		listPush(l, end, v.(string))
+	}
+	storeContainer(s, key, l)
+	return IntegerReply(l.Len()), nil
+}
+
+// pushxCommand pushes elements to one end of a list, only if it exists.
+func pushxCommand(c *Client, end string, ca ...any) (Reply, error) {
+	s := c.db()
+	key := ca[0].(string)
+	l, err := lookupList(s, key)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil {
+		return IntegerReply(0), nil
+	}
+	for _, v := range ca[1:] {
+		listPush(l, end, v.(string))
+	}
+	storeContainer(s, key, l)
+	return IntegerReply(l.Len()), nil
+}
+
+func blpop(c *Client, ca ...any) (Reply, error) {
+	return bpopCommand(c, listLeft, ca...)
+}
+
+func brpop(c *Client, ca ...any) (Reply, error) {
+	return bpopCommand(c, listRight, ca...)
+}
+
+// bpopCommand pops an element from one end of the first non-empty list
+// among the keys, and replies with its key and the element. It blocks until
+// one of the lists has elements otherwise.
+func bpopCommand(c *Client, end string, ca ...any) (Reply, error) {
+	timeout, err := parseTimeout(ca[len(ca)-1].(string), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(ca)-1)
+	for i := range keys {
+		keys[i] = ca[i].(string)
+	}
+	return c.block(keys, timeout, func() (Reply, error) {
+		r, err := mpop(c.db(), keys, end, 1)
+		if r == nil || err != nil {
+			return r, err
+		}
+		popped := r.(ArrayReply)
+		return ArrayReply{popped[0], popped[1].(ArrayReply)[0]}, nil
+	})
+}
+
+// blmove is LMOVE, blocking until the source list has elements.
+func blmove(c *Client, ca ...any) (Reply, error) {
+	src, dst := ca[0].(string), ca[1].(string)
+	from, err := parseListEnd(ca[2].(string))
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseListEnd(ca[3].(string))
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := parseTimeout(ca[4].(string), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return c.block([]string{src}, timeout, func() (Reply, error) {
+		s := c.db()
+		if l, err := lookupList(s, src); l == nil || err != nil {
+			return nil, err
+		}
+		r, err := listMove(s, src, dst, from, to)
+		// Clients blocked on dst may be served in turn.
+		c.server.signalKeyAsReady(c.dbIndex, dst)
+		return r, err
+	})
+}
+
+// blmpop is LMPOP, blocking until one of the lists has elements.
+func blmpop(c *Client, ca ...any) (Reply, error) {
+	keys, end, count, err := parseMPop(ca[1:])
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := parseTimeout(ca[0].(string), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return c.block(keys, timeout, func() (Reply, error) {
+		return mpop(c.db(), keys, end, count)
+	})
+}
@@ -0,0 +1,307 @@
+package redis
+
+import (
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Avik32223/redis-server/internal/rediserr"
+)
+
+// Command flags, as reported by COMMAND.
+const (
+	flagWrite    = "write"
+	flagReadonly = "readonly"
+	flagDenyOOM  = "denyoom"
+	flagFast     = "fast"
+	flagLoading  = "loading"
+	flagStale    = "stale"
+	flagNoScript = "noscript"
+	flagBlocking = "blocking"
+	// flagMovableKeys marks commands whose keys are not found
+	// by firstKey, lastKey and keyStep alone.
+	flagMovableKeys = "movablekeys"
+)
+
+// commandSpec is a command registered with the server,
+// along with the metadata COMMAND reports about it.
+type commandSpec struct {
+	name string
+	run  Command
+
+	// arity is the number of arguments, the command name included.
+	// A negative arity is the minimum number of arguments of a variadic command.
+	arity int
+	flags []string
+	// firstKey, lastKey and keyStep locate the keys among the arguments.
+	// A negative lastKey counts from the end, -1 being the last argument.
+	// A command without keys has a firstKey of 0.
+	firstKey, lastKey, keyStep int
+	// keyNumIndex, for commands taking a number of keys followed by the keys
+	// themselves, is the index of the number. Their firstKey is then 0.
+	keyNumIndex int
+	// keyFlags describe how keys are accessed, as "RW" and "UPDATE".
+	keyFlags []string
+	// categories are the ACL categories of the command, as "@string".
+	categories []string
+
+	group      string
+	since      string
+	summary    string
+	complexity string
+}
+
+// commandTable maps the lowercase name of every command to its spec.
+var commandTable = map[string]*commandSpec{}
+
+func init() {
+	for _, group := range [][]*commandSpec{commands, stringCommands, bitmapCommands, bigNumberCommands, listCommands, hashCommands} {
+		for _, c := range group {
+			commandTable[c.name] = c
+		}
+	}
+}
+
+// lookupCommand returns the spec of the command named name, in any case.
+func lookupCommand(name string) (*commandSpec, bool) {
+	c, ok := commandTable[strings.ToLower(name)]
+	return c, ok
+}
+
+// checkArity reports whether argc arguments, command name included, suit c.
+func (c *commandSpec) checkArity(argc int) bool {
+	if c.arity > 0 {
+		return argc == c.arity
+	}
+	return argc >= -c.arity
+}
+
+// keys returns the indexes of the keys in args, command name included.
+func (c *commandSpec) keys(args []any) []int {
+	if c.keyNumIndex != 0 {
+		n, err := parseInt(args[c.keyNumIndex].(string))
+		if err != nil || n < 1 || n >= int64(len(args)-c.keyNumIndex) {
+			return nil
+		}
+		keys := make([]int, n)
+		for i := range keys {
+			keys[i] = c.keyNumIndex + 1 + i
+		}
+		return keys
+	}
+	if c.firstKey == 0 {
+		return nil
+	}
+	last := c.lastKey
+	if last < 0 {
+		last = len(args) + last
+	}
+	keys := make([]int, 0)
+	for i := c.firstKey; i <= last && i < len(args); i += c.keyStep {
+		keys = append(keys, i)
+	}
+	return keys
+}
+
+func statusSet(s []string) SetReply {
+	res := make(SetReply, 0, len(s))
+	for _, i := range s {
+		res = append(res, StatusReply(i))
+	}
+	return res
+}
+
+// info is the reply of COMMAND INFO for c.
+func (c *commandSpec) info() Reply {
+	keySpecs := ArrayReply{}
+	if c.keyNumIndex != 0 {
+		keySpecs = append(keySpecs, MapReply{
+			{Key: BulkReply("flags"), Value: statusSet(c.keyFlags)},
+			{Key: BulkReply("begin_search"), Value: MapReply{
+				{Key: BulkReply("type"), Value: BulkReply("index")},
+				{Key: BulkReply("spec"), Value: MapReply{
+					{Key: BulkReply("index"), Value: IntegerReply(c.keyNumIndex)},
+				}},
+			}},
+			{Key: BulkReply("find_keys"), Value: MapReply{
+				{Key: BulkReply("type"), Value: BulkReply("keynum")},
+				{Key: BulkReply("spec"), Value: MapReply{
+					{Key: BulkReply("keynumidx"), Value: IntegerReply(0)},
+					{Key: BulkReply("firstkey"), Value: IntegerReply(1)},
+					{Key: BulkReply("keystep"), Value: IntegerReply(1)},
+				}},
+			}},
+		})
+	}
+	if c.firstKey != 0 {
+		lastKey := c.lastKey
+		if lastKey > 0 {
+			lastKey -= c.firstKey
+		}
+		keySpecs = append(keySpecs, MapReply{
+			{Key: BulkReply("flags"), Value: statusSet(c.keyFlags)},
+			{Key: BulkReply("begin_search"), Value: MapReply{
+				{Key: BulkReply("type"), Value: BulkReply("index")},
+				{Key: BulkReply("spec"), Value: MapReply{
+					{Key: BulkReply("index"), Value: IntegerReply(c.firstKey)},
+				}},
+			}},
+			{Key: BulkReply("find_keys"), Value: MapReply{
+				{Key: BulkReply("type"), Value: BulkReply("range")},
+				{Key: BulkReply("spec"), Value: MapReply{
+					{Key: BulkReply("lastkey"), Value: IntegerReply(lastKey)},
+					{Key: BulkReply("keystep"), Value: IntegerReply(c.keyStep)},
+					{Key: BulkReply("limit"), Value: IntegerReply(0)},
+				}},
+			}},
+		})
+	}
+	return ArrayReply{
+		BulkReply(c.name),
+		IntegerReply(c.arity),
+		statusSet(c.flags),
+		IntegerReply(c.firstKey),
+		IntegerReply(c.lastKey),
+		IntegerReply(c.keyStep),
+		statusSet(c.categories),
+		SetReply{},
+		keySpecs,
+		ArrayReply{},
+	}
+}
+
+// docs is the reply of COMMAND DOCS for c. Like redis does for module
+// commands, it leaves since out for commands that are not redis'.
+func (c *commandSpec) docs() Reply {
+	docs := MapReply{{Key: BulkReply("summary"), Value: BulkReply(c.summary)}}
+	if c.since != "" {
+		docs = append(docs, KeyValue{Key: BulkReply("since"), Value: BulkReply(c.since)})
+	}
+	return append(docs,
+		KeyValue{Key: BulkReply("group"), Value: BulkReply(c.group)},
+		KeyValue{Key: BulkReply("complexity"), Value: BulkReply(c.complexity)},
+	)
+}
+
+// sortedCommands returns every command, ordered by name.
+func sortedCommands() []*commandSpec {
+	res := make([]*commandSpec, 0, len(commandTable))
+	for _, c := range commandTable {
+		res = append(res, c)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].name < res[j].name })
+	return res
+}
+
+func command(c *Client, ca ...any) (Reply, error) {
+	if len(ca) == 0 {
+		res := make(ArrayReply, 0, len(commandTable))
+		for _, c := range sortedCommands() {
+			res = append(res, c.info())
+		}
+		return res, nil
+	}
+
+	sub := strings.ToLower(ca[0].(string))
+	args := ca[1:]
+	switch sub {
+	case "count":
+		if len(args) == 0 {
+			return IntegerReply(len(commandTable)), nil
+		}
+	case "info", "docs":
+		specs := sortedCommands()
+		if len(args) > 0 {
+			specs = make([]*commandSpec, 0, len(args))
+			for _, a := range args {
+				c, _ := lookupCommand(a.(string))
+				specs = append(specs, c)
+			}
+		}
+		if sub == "info" {
+			res := make(ArrayReply, 0, len(specs))
+			for _, c := range specs {
+				if c == nil {
+					res = append(res, NullArrayReply{})
+					continue
+				}
+				res = append(res, c.info())
+			}
+			return res, nil
+		}
+		// Unknown commands are left out of the docs.
+		res := make(MapReply, 0, len(specs))
+		for _, c := range specs {
+			if c != nil {
+				res = append(res, KeyValue{Key: BulkReply(c.name), Value: c.docs()})
+			}
+		}
+		return res, nil
+	case "list":
+		return commandList(args...)
+	case "getkeys":
+		if len(args) > 0 {
+			return commandGetKeys(args...)
+		}
+	default:
+		return nil, rediserr.UnknownSubcommand("command", ca[0].(string))
+	}
+	return nil, rediserr.WrongArity("command|" + sub)
+}
+
+// commandList replies with the names of the commands,
+// optionally filtered by ACL category or by a glob-style pattern.
+func commandList(ca ...any) (Reply, error) {
+	match := func(*commandSpec) bool { return true }
+	if len(ca) > 0 {
+		if len(ca) != 3 || strings.ToLower(ca[0].(string)) != "filterby" {
+			return nil, rediserr.Syntax
+		}
+		v := ca[2].(string)
+		switch strings.ToLower(ca[1].(string)) {
+		case "module":
+			// Modules are not supported, no command belongs to one.
+			match = func(*commandSpec) bool { return false }
+		case "aclcat":
+			match = func(c *commandSpec) bool {
+				return slices.ContainsFunc(c.categories, func(cat string) bool {
+					return strings.EqualFold(cat, "@"+v)
+				})
+			}
+		case "pattern":
+			match = func(c *commandSpec) bool {
+				ok, _ := path.Match(strings.ToLower(v), c.name)
+				return ok
+			}
+		default:
+			return nil, rediserr.Syntax
+		}
+	}
+	res := ArrayReply{}
+	for _, c := range sortedCommands() {
+		if match(c) {
+			res = append(res, BulkReply(c.name))
+		}
+	}
+	return res, nil
+}
+
+func commandGetKeys(ca ...any) (Reply, error) {
+	c, ok := lookupCommand(ca[0].(string))
+	if !ok {
+		return nil, rediserr.New(rediserr.ERR, "Invalid command specified")
+	}
+	if !c.checkArity(len(ca)) {
+		return nil, rediserr.New(rediserr.ERR, "Invalid number of arguments specified for command")
+	}
+	keys := c.keys(ca)
+	if len(keys) == 0 {
+		return nil, rediserr.New(rediserr.ERR, "The command has no key arguments")
+	}
+	res := make(ArrayReply, 0, len(keys))
+	for _, i := range keys {
+		res = append(res, BulkReply(ca[i].(string)))
+	}
+	return res, nil
+}
@@ -0,0 +1,35 @@
+package redis
+
+import "github.com/Avik32223/redis-server/pkg/resp"
+
+// Reply is the value a command replies with. Every reply type maps to
+// exactly one RESP type, which is rendered for the protocol the client
+// speaks by Serialize. The reply types are those of package resp, whose
+// Decoder reads them back.
+type Reply = resp.Reply
+
+type (
+	StatusReply    = resp.StatusReply
+	BulkReply      = resp.BulkReply
+	NullReply      = resp.NullReply
+	NullBulkReply  = resp.NullBulkReply
+	NullArrayReply = resp.NullArrayReply
+	IntegerReply   = resp.IntegerReply
+	DoubleReply    = resp.DoubleReply
+	BooleanReply   = resp.BooleanReply
+	ArrayReply     = resp.ArrayReply
+	MapReply       = resp.MapReply
+	KeyValue       = resp.KeyValue
+	SetReply       = resp.SetReply
+	PushReply      = resp.PushReply
+	VerbatimReply  = resp.VerbatimReply
+	BigNumberReply = resp.BigNumberReply
+	AttributeReply = resp.AttributeReply
+	ErrorReply     = resp.ErrorReply
+	BulkErrorReply = resp.BulkErrorReply
+)
+
+var (
+	okReply    = StatusReply("OK")
+	emptyArray = ArrayReply{}
+)
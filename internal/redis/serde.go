@@ -3,3 +3,8 @@ package redis
 type SerDeOpts map[string]string
 
 var defaultSerdeOpts SerDeOpts = SerDeOpts{}
+
+// resp3 reports whether replies are rendered for RESP3, RESP2 being the default.
+func (o SerDeOpts) resp3() bool {
+	return o["resp_version"] == "3"
+}
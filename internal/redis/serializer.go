@@ -4,19 +4,19 @@ import (
 	"fmt"
 	"math"
 	"math/big"
-	"reflect"
+	"strconv"
 	"strings"
-	"unicode"
-
-	"github.com/Avik32223/redis-server/pkg/lists"
 )
 
 func SerializeSimpleString(m string, opts *SerDeOpts) (string, error) {
 	return fmt.Sprintf("+%s\r\n", m), nil
 }
 
+// SerializeSimpleError renders err on a single line,
+// line breaks in its message are replaced by spaces.
 func SerializeSimpleError(err error, opts *SerDeOpts) (string, error) {
-	return fmt.Sprintf("-%s\r\n", err), nil
+	m := strings.NewReplacer("\r", " ", "\n", " ").Replace(err.Error())
+	return fmt.Sprintf("-%s\r\n", m), nil
 }
 
 func SerializeInt(m int64, opts *SerDeOpts) (string, error) {
@@ -27,13 +27,46 @@ func SerializeBulkString(m string, opts *SerDeOpts) (string, error) {
 	return fmt.Sprintf("$%d\r\n%s\r\n", len(m), m), nil
 }
 
+// SerializeNull renders the RESP3 null, which RESP2 only has for strings and arrays.
+// A string null is used then.
 func SerializeNull(opts *SerDeOpts) (string, error) {
+	if opts.resp3() {
+		return "_\r\n", nil
+	}
 	return "$-1\r\n", nil
 }
 
-func SerializeArray(m []any, opts *SerDeOpts) (string, error) {
+func SerializeNullArray(opts *SerDeOpts) (string, error) {
+	if opts.resp3() {
+		return "_\r\n", nil
+	}
+	return "*-1\r\n", nil
+}
+
+func SerializeArray(m []Reply, opts *SerDeOpts) (string, error) {
+	return serializeAggregate('*', m, opts)
+}
+
+// SerializeSet renders m as a RESP3 set, or as an array for RESP2.
+func SerializeSet(m []Reply, opts *SerDeOpts) (string, error) {
+	if !opts.resp3() {
+		return SerializeArray(m, opts)
+	}
+	return serializeAggregate('~', m, opts)
+}
+
+// SerializePush renders m as a RESP3 push, or as an array for RESP2.
+func SerializePush(m []Reply, opts *SerDeOpts) (string, error) {
+	if !opts.resp3() {
+		return SerializeArray(m, opts)
+	}
+	return serializeAggregate('>', m, opts)
+}
+
+func serializeAggregate(prefix rune, m []Reply, opts *SerDeOpts) (string, error) {
 	s := new(strings.Builder)
-	s.WriteString(fmt.Sprintf("*%d\r\n", len(m)))
+	s.WriteRune(prefix)
+	s.WriteString(fmt.Sprintf("%d\r\n", len(m)))
 	for _, i := range m {
 		result, err := Serialize(i, opts)
 		if err != nil {
@@ -44,53 +77,123 @@ func SerializeArray(m []any, opts *SerDeOpts) (string, error) {
 	return s.String(), nil
 }
 
+// SerializeBoolean renders m as a RESP3 boolean, or as the integer 1 or 0 for RESP2.
 func SerializeBoolean(m bool, opts *SerDeOpts) (string, error) {
+	if !opts.resp3() {
+		if m {
+			return SerializeInt(1, opts)
+		}
+		return SerializeInt(0, opts)
+	}
 	if m {
 		return "#t\r\n", nil
 	}
 	return "#f\r\n", nil
 }
 
+// SerializeDouble renders m as a RESP3 double, or as a bulk string for RESP2.
 func SerializeDouble(m float64, opts *SerDeOpts) (string, error) {
-	if math.IsInf(m, 1) {
-		return ",inf\r\n", nil
+	d := formatDouble(m)
+	if !opts.resp3() {
+		return SerializeBulkString(d, opts)
 	}
-	if math.IsInf(m, -1) {
-		return ",-inf\r\n", nil
+	return fmt.Sprintf(",%s\r\n", d), nil
+}
+
+// formatDouble formats m the way redis does: in the shortest representation
+// that reads back as m, without exponent unless m is very large or small.
+func formatDouble(m float64) string {
+	switch {
+	case math.IsInf(m, 1):
+		return "inf"
+	case math.IsInf(m, -1):
+		return "-inf"
+	case math.IsNaN(m):
+		return "nan"
 	}
-	if math.IsNaN(m) {
-		return ",nan\r\n", nil
+	if a := math.Abs(m); a != 0 && (a < 1e-7 || a >= 1e21) {
+		return strconv.FormatFloat(m, 'g', -1, 64)
 	}
-	return fmt.Sprintf(",%e\r\n", m), nil
+	return strconv.FormatFloat(m, 'f', -1, 64)
 }
 
+// SerializeBigNumber renders m as a RESP3 big number, or as a bulk string for RESP2.
+// m is a big.Int, big.Float or big.Rat, or a pointer to one, holding an integer.
 func SerializeBigNumber(m any, opts *SerDeOpts) (string, error) {
+	var n *big.Int
 	switch m := m.(type) {
+	case *big.Int:
+		n = m
 	case big.Int:
-		return fmt.Sprintf("(%s\r\n", m.String()), nil
+		n = &m
+	case *big.Float:
+		if m.IsInt() {
+			n, _ = m.Int(nil)
+		}
 	case big.Float:
-		return fmt.Sprintf("(%s\r\n", m.String()), nil
+		if m.IsInt() {
+			n, _ = m.Int(nil)
+		}
+	case *big.Rat:
+		if m.IsInt() {
+			n = m.Num()
+		}
 	case big.Rat:
-		return fmt.Sprintf("(%s\r\n", m.String()), nil
+		if m.IsInt() {
+			n = m.Num()
+		}
+	}
+	if n == nil {
+		return "", fmt.Errorf("big number type not supported: %#v", m)
+	}
+	if !opts.resp3() {
+		return SerializeBulkString(n.String(), opts)
 	}
-	return "", fmt.Errorf("big number type not supported: %#v", m)
+	return fmt.Sprintf("(%s\r\n", n.String()), nil
 }
 
+// SerializeBulkError renders m as a RESP3 bulk error,
+// or as a simple error for RESP2.
 func SerializeBulkError(m error, opts *SerDeOpts) (string, error) {
+	if !opts.resp3() {
+		return SerializeSimpleError(m, opts)
+	}
 	return fmt.Sprintf("!%d\r\n%s\r\n", len(m.Error()), m), nil
 }
 
-func SerializeVerbatimString(m string, opts *SerDeOpts) (string, error) {
-	return "", nil
+// SerializeVerbatimString renders m as a RESP3 verbatim string,
+// or as a bulk string of its text for RESP2.
+func SerializeVerbatimString(m VerbatimReply, opts *SerDeOpts) (string, error) {
+	if len(m.Format) != 3 {
+		return "", fmt.Errorf("verbatim string format must be 3 bytes long: %q", m.Format)
+	}
+	if !opts.resp3() {
+		return SerializeBulkString(m.Text, opts)
+	}
+	return fmt.Sprintf("=%d\r\n%s:%s\r\n", len(m.Text)+4, m.Format, m.Text), nil
+}
+
+// SerializeAttribute renders m.Reply preceded by its attributes.
+// RESP2 has no attributes, they are left out then.
+func SerializeAttribute(m AttributeReply, opts *SerDeOpts) (string, error) {
+	r, err := Serialize(m.Reply, opts)
+	if err != nil || !opts.resp3() {
+		return r, err
+	}
+	a, err := SerializeMap(m.Attributes, opts)
+	if err != nil {
+		return "", err
+	}
+	return "|" + a[1:] + r, nil
 }
 
-func SerializeMap(m map[string]any, opts *SerDeOpts) (string, error) {
-	resp_version, ok := (*opts)["resp_version"]
-	if ok && resp_version == "2" {
-		l := make([]any, 0)
-		for k, v := range m {
-			l = append(l, k)
-			l = append(l, v)
+// SerializeMap renders m as a RESP3 map,
+// or as an array of alternating keys and values for RESP2.
+func SerializeMap(m []KeyValue, opts *SerDeOpts) (string, error) {
+	if !opts.resp3() {
+		l := make([]Reply, 0, 2*len(m))
+		for _, kv := range m {
+			l = append(l, kv.Key, kv.Value)
 		}
 		return SerializeArray(l, opts)
 	}
@@ -98,12 +201,12 @@ func SerializeMap(m map[string]any, opts *SerDeOpts) (string, error) {
 	s := new(strings.Builder)
 	s.WriteRune('%')
 	s.WriteString(fmt.Sprintf("%d\r\n", len(m)))
-	for k, v := range m {
-		kS, err := Serialize(k, opts)
+	for _, kv := range m {
+		kS, err := Serialize(kv.Key, opts)
 		if err != nil {
 			return "", err
 		}
-		kV, err := Serialize(v, opts)
+		kV, err := Serialize(kv.Value, opts)
 		if err != nil {
 			return "", err
 		}
@@ -114,74 +217,49 @@ func SerializeMap(m map[string]any, opts *SerDeOpts) (string, error) {
 }
 
 func SerializeError(m error, opts *SerDeOpts) (string, error) {
-	bulkError := strings.ContainsFunc(m.Error(), func(r rune) bool {
-		if unicode.IsControl(r) {
-			return true
-		}
-		if r == '\n' || r == '\r' {
-			return true
-		}
-		return false
-	})
-	if bulkError {
-		return SerializeBulkError(m, opts)
-	}
 	return SerializeSimpleError(m, opts)
-
-}
-
-func SerializeString(m string, opts *SerDeOpts) (string, error) {
-	verbatim, ok := (*opts)["verbatim"]
-	if ok && len(verbatim) == 3 {
-		return SerializeVerbatimString(m, opts)
-	}
-	isBulkString := strings.ContainsFunc(m, func(r rune) bool {
-		if unicode.IsControl(r) {
-			return true
-		}
-		if r == '\n' || r == '\r' {
-			return true
-		}
-		return false
-	})
-	if isBulkString {
-		return SerializeBulkString(m, opts)
-	}
-	return SerializeSimpleString(m, opts)
 }
 
+// Serialize renders a command reply, or the error it failed with,
+// for the protocol selected in opts.
 func Serialize(m any, opts *SerDeOpts) (string, error) {
 	if opts == nil {
 		opts = &defaultSerdeOpts
 	}
 
-	if m == nil {
-		return SerializeNull(opts)
-	}
-
-	rv := reflect.ValueOf(m)
-	switch rv.Kind() {
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return SerializeInt(rv.Int(), opts)
-	case reflect.Float32, reflect.Float64:
-		return SerializeDouble(rv.Float(), opts)
-	case reflect.Bool:
-		return SerializeBoolean(rv.Bool(), opts)
-	case reflect.Slice, reflect.Array:
-		return SerializeArray(rv.Interface().([]any), opts)
-	case reflect.Map:
-		x := rv.Interface().(map[string]any)
-		return SerializeMap(x, opts)
-	case reflect.TypeOf(lists.List{}).Kind():
-		v := rv.Interface().(lists.List)
-		return SerializeArray(v.ToSlice(), opts)
-	}
-
 	switch mt := m.(type) {
-	case big.Int, big.Float, big.Rat:
-		return SerializeBigNumber(mt, opts)
-	case string:
-		return SerializeString(mt, opts)
+	case StatusReply:
+		return SerializeSimpleString(string(mt), opts)
+	case BulkReply:
+		return SerializeBulkString(string(mt), opts)
+	case NullBulkReply:
+		return SerializeNull(opts)
+	case NullArrayReply:
+		return SerializeNullArray(opts)
+	case IntegerReply:
+		return SerializeInt(int64(mt), opts)
+	case DoubleReply:
+		return SerializeDouble(float64(mt), opts)
+	case ArrayReply:
+		return SerializeArray(mt, opts)
+	case MapReply:
+		return SerializeMap(mt, opts)
+	case SetReply:
+		return SerializeSet(mt, opts)
+	case PushReply:
+		return SerializePush(mt, opts)
+	case VerbatimReply:
+		return SerializeVerbatimString(mt, opts)
+	case BigNumberReply:
+		return SerializeBigNumber(mt.Int, opts)
+	case NullReply:
+		return SerializeNull(opts)
+	case BooleanReply:
+		return SerializeBoolean(bool(mt), opts)
+	case AttributeReply:
+		return SerializeAttribute(mt, opts)
+	case BulkErrorReply:
+		return SerializeBulkError(mt, opts)
 	case error:
 		return SerializeError(mt, opts)
 	}
@@ -0,0 +1,113 @@
+package redis
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Avik32223/redis-server/pkg/resp"
+)
+
+func TestSerialize(t *testing.T) {
+	resp2 := &SerDeOpts{"resp_version": "2"}
+	resp3 := &SerDeOpts{"resp_version": "3"}
+	type testCase struct {
+		name string
+		m    any
+		opts *SerDeOpts
+		want string
+	}
+	tests := []testCase{
+		{"status", StatusReply("OK"), nil, "+OK\r\n"},
+		{"bulk", BulkReply("OK"), nil, "$2\r\nOK\r\n"},
+		{"bulk-binary", BulkReply("a\r\n\x00"), nil, "$4\r\na\r\n\x00\r\n"},
+		{"bulk-empty", BulkReply(""), nil, "$0\r\n\r\n"},
+		{"null-bulk", NullBulkReply{}, resp2, "$-1\r\n"},
+		{"null-array", NullArrayReply{}, resp2, "*-1\r\n"},
+		{"integer", IntegerReply(-42), nil, ":-42\r\n"},
+		{"double-resp2", DoubleReply(3.5), resp2, "$3\r\n3.5\r\n"},
+		{"double-resp3", DoubleReply(3.5), resp3, ",3.5\r\n"},
+		{"double-integral", DoubleReply(10), resp3, ",10\r\n"},
+		{"double-inf", DoubleReply(math.Inf(-1)), resp3, ",-inf\r\n"},
+		{"double-large", DoubleReply(1e300), resp3, ",1e+300\r\n"},
+		{"array", ArrayReply{BulkReply("a"), IntegerReply(1), NullBulkReply{}}, nil, "*3\r\n$1\r\na\r\n:1\r\n$-1\r\n"},
+		{"array-empty", ArrayReply{}, nil, "*0\r\n"},
+		{"map-resp2", MapReply{{Key: BulkReply("a"), Value: IntegerReply(1)}, {Key: BulkReply("b"), Value: IntegerReply(2)}}, resp2, "*4\r\n$1\r\na\r\n:1\r\n$1\r\nb\r\n:2\r\n"},
+		{"map-resp3", MapReply{{Key: BulkReply("a"), Value: IntegerReply(1)}, {Key: BulkReply("b"), Value: IntegerReply(2)}}, resp3, "%2\r\n$1\r\na\r\n:1\r\n$1\r\nb\r\n:2\r\n"},
+		{"big-number-resp2", BigNumberReply{Int: big.NewInt(12)}, resp2, "$2\r\n12\r\n"},
+		{"big-number-resp3", BigNumberReply{Int: big.NewInt(12)}, resp3, "(12\r\n"},
+		{"null-bulk-resp3", NullBulkReply{}, resp3, "_\r\n"},
+		{"null-array-resp3", NullArrayReply{}, resp3, "_\r\n"},
+		{"null-resp2", NullReply{}, resp2, "$-1\r\n"},
+		{"boolean-resp2", BooleanReply(true), resp2, ":1\r\n"},
+		{"boolean-resp3", BooleanReply(false), resp3, "#f\r\n"},
+		{"set-resp2", SetReply{BulkReply("a")}, resp2, "*1\r\n$1\r\na\r\n"},
+		{"set-resp3", SetReply{BulkReply("a")}, resp3, "~1\r\n$1\r\na\r\n"},
+		{"push-resp2", PushReply{BulkReply("message")}, resp2, "*1\r\n$7\r\nmessage\r\n"},
+		{"push-resp3", PushReply{BulkReply("message")}, resp3, ">1\r\n$7\r\nmessage\r\n"},
+		{"verbatim-resp2", VerbatimReply{Format: "txt", Text: "Some string"}, resp2, "$11\r\nSome string\r\n"},
+		{"verbatim-resp3", VerbatimReply{Format: "txt", Text: "Some string"}, resp3, "=15\r\ntxt:Some string\r\n"},
+		{"attribute-resp2", AttributeReply{Attributes: MapReply{{Key: BulkReply("ttl"), Value: IntegerReply(3)}}, Reply: IntegerReply(1)}, resp2, ":1\r\n"},
+		{"attribute-resp3", AttributeReply{Attributes: MapReply{{Key: BulkReply("ttl"), Value: IntegerReply(3)}}, Reply: IntegerReply(1)}, resp3, "|1\r\n$3\r\nttl\r\n:3\r\n:1\r\n"},
+		{"big-number-value", BigNumberReply{Int: new(big.Int).Lsh(big.NewInt(1), 100)}, resp3, "(1267650600228229401496703205376\r\n"},
+		{"bulk-error-resp2", BulkErrorReply("SYNTAX invalid\nsyntax"), resp2, "-SYNTAX invalid syntax\r\n"},
+		{"bulk-error-resp3", BulkErrorReply("SYNTAX invalid\nsyntax"), resp3, "!21\r\nSYNTAX invalid\nsyntax\r\n"},
+		{"error", errors.New("ERR bad\r\nthing"), nil, "-ERR bad  thing\r\n"},
+		{"error-reply", ArrayReply{ErrorReply("ERR nested")}, nil, "*1\r\n-ERR nested\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Serialize(tt.m, tt.opts)
+			if err != nil {
+				t.Fatalf("Serialize() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Serialize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSerializeBigNumber(t *testing.T) {
+	resp3 := &SerDeOpts{"resp_version": "3"}
+	for _, m := range []any{big.NewInt(42), *big.NewInt(42), big.NewFloat(42), big.NewRat(84, 2)} {
+		got, err := SerializeBigNumber(m, resp3)
+		if err != nil || got != "(42\r\n" {
+			t.Errorf("SerializeBigNumber(%v) = %q, %v, want %q", m, got, err, "(42\r\n")
+		}
+	}
+	for _, m := range []any{big.NewFloat(4.2), big.NewRat(1, 3), 42, (*big.Int)(nil)} {
+		if _, err := SerializeBigNumber(m, resp3); err == nil {
+			t.Errorf("SerializeBigNumber(%v) error = nil, want an error", m)
+		}
+	}
+}
+
+func TestDecoderRoundTrip(t *testing.T) {
+	resp3 := &SerDeOpts{"resp_version": "3"}
+	want := ArrayReply{
+		StatusReply("OK"), BulkReply("x\r\ny"), IntegerReply(7), DoubleReply(0.25), BooleanReply(false),
+		NullReply{}, VerbatimReply{Format: "mkd", Text: "# title"}, BigNumberReply{Int: bigInt("123456789012345678901234567890")},
+		MapReply{{Key: BulkReply("k"), Value: SetReply{BulkReply("v")}}}, PushReply{BulkReply("invalidate")},
+		ErrorReply("ERR nested"),
+	}
+	s, err := Serialize(want, resp3)
+	if err != nil {
+		t.Fatalf("Serialize() error = %v", err)
+	}
+	got, err := resp.NewDecoder(strings.NewReader(s)).Decode()
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode() = %#v, want %#v", got, want)
+	}
+}
+
+func bigInt(s string) *big.Int {
+	n, _ := new(big.Int).SetString(s, 10)
+	return n
+}
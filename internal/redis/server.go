@@ -2,12 +2,21 @@ package redis
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 
 	"github.com/Avik32223/redis-server/internal/transport"
 )
 
+const (
+	// minReadSize is the smallest chunk read from a connection at once.
+	minReadSize = 16 * 1024
+	// dbCount is the number of databases clients can select.
+	dbCount = 16
+)
+
 type servermode string
 
 const standalone servermode = "standalone"
@@ -18,71 +27,89 @@ type Server struct {
 	Transport transport.Transport
 	quitCh    chan struct{}
 
-	state State
+	dbs []State
+
+	// clients holds the client of every connected peer.
+	clients      map[transport.Peer]*Client
+	lastClientID int64
+
+	// blockingKeys queues the clients blocked on every key, in the order
+	// they blocked. readyQueue holds the keys modified since clients were
+	// last served, readyKeys the same keys as a set.
+	blockingKeys map[blockingKey][]*blockedClient
+	readyKeys    map[blockingKey]bool
+	readyQueue   []blockingKey
+	// timeoutCh receives the blocked clients whose timeout expired.
+	timeoutCh chan *blockedClient
 }
 
 func NewServer(addr string) *Server {
 	t := transport.NewTCPTransport(addr)
-	t.Receive = receive
+	t.NewReceiver = newRESPReceiver
 
 	s := Server{
 		id:        "default",
 		mode:      standalone,
 		Transport: t,
-		state:     NewState(),
+		quitCh:    make(chan struct{}),
+		dbs:       make([]State, dbCount),
+		clients:   make(map[transport.Peer]*Client),
+
+		blockingKeys: make(map[blockingKey][]*blockedClient),
+		readyKeys:    make(map[blockingKey]bool),
+		timeoutCh:    make(chan *blockedClient),
+	}
+	for i := range s.dbs {
+		s.dbs[i] = NewState()
 	}
 	return &s
 }
 
-func receive(i io.Reader) ([]byte, error) {
-	scanner := bufio.NewScanner(i)
-
-	// The custom split method helps us accumulate buffered incomming data and split them by valid msessages.
-	scanner.Split(func(data []byte, atEOF bool) (advance int, token []byte, err error) {
-		if atEOF && len(data) == 0 {
-			return 0, nil, nil
-		}
+// respReceiver frames the byte stream of a single connection into redis
+// requests. Bytes read past the end of a request are kept for the next
+// call, so requests that arrive pipelined in a single write are returned
+// one by one and none of them is dropped.
+type respReceiver struct {
+	r   io.Reader
+	buf []byte
+}
 
-		// Redis clients always communicate by sending commands as an arry of bulk strings.
-		// Hoping to find a valid array from the 0th index
-		if rune(data[0]) == '*' {
-			c := eatArray(data, 0)
+func newRESPReceiver(r *bufio.Reader) transport.Receiver {
+	return &respReceiver{r: r}
+}
 
-			// if a valid redis client message is found,
-			// we split the data into a valid token and return.
-			// This helps is pipelining commands (data contains multiple commands).
-			if c > 0 {
-				return c, data[:c], nil
-			}
-			// This handles the case when we couldn't find
-			// a valid array of bulk strings (presumably from a redis client).
-			// We will still need to validate the whole message later
-			// as redis supports inline communication.
-			// We return the complete message to be processed later.
-			if atEOF {
-				return 0, data, bufio.ErrFinalToken
+func (rr *respReceiver) Receive() ([]byte, error) {
+	for {
+		args, n, need, err := parseRequest(rr.buf)
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			msg := bytes.Clone(rr.buf[:n])
+			rr.buf = rr.buf[n:]
+			// Empty requests are skipped, as redis does.
+			if len(args) == 0 {
+				continue
 			}
-
-			// Request more data
-			return 0, nil, nil
-
-		} else {
-			// If the start of a message does not hint of a redis command.
-			// We'll assume its an inline command
-			return bufio.ScanLines(data, atEOF)
+			return msg, nil
 		}
-	})
 
-	if scanner.Scan() {
-		b := scanner.Bytes()
-		return b, nil
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, err
+		// Read at least as much as the parser needs in one go, so a large
+		// bulk string is not parsed again for every segment it arrives in.
+		if free := cap(rr.buf) - len(rr.buf); free < need || free < minReadSize {
+			buf := make([]byte, len(rr.buf), len(rr.buf)+max(need, minReadSize))
+			copy(buf, rr.buf)
+			rr.buf = buf
+		}
+		c, err := io.ReadAtLeast(rr.r, rr.buf[len(rr.buf):cap(rr.buf)], need)
+		rr.buf = rr.buf[:len(rr.buf)+c]
+		if err != nil {
+			if err == io.ErrUnexpectedEOF || (err == io.EOF && len(rr.buf) > 0) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
 	}
-
-	return nil, io.EOF
 }
 
 func (s *Server) Start() error {
@@ -96,6 +123,9 @@ func (s *Server) Start() error {
 		case msg := <-s.Transport.Consume():
 			s.HandleMessage(msg)
 
+		case b := <-s.timeoutCh:
+			s.timeoutBlocked(b)
+
 		case <-s.quitCh:
 			return nil
 		}
@@ -107,16 +137,43 @@ func (s *Server) Stop() error {
 	return nil
 }
 
+// client returns the client of peer, creating it on its first message.
+func (s *Server) client(peer transport.Peer) *Client {
+	c, ok := s.clients[peer]
+	if !ok {
+		s.lastClientID++
+		c = newClient(s, s.lastClientID, peer)
+		s.clients[peer] = c
+	}
+	return c
+}
+
 func (s *Server) HandleMessage(m transport.Message) error {
-	x, err := RunCommand(s.state, m.Payload)
-	if err != nil {
-		x, _ := Serialize(err, nil)
-		return m.Peer.Send([]byte(x))
+	c := s.client(m.Peer)
+	if m.Err != nil {
+		if c.blocked != nil {
+			s.unblock(c.blocked)
+		}
+		delete(s.clients, m.Peer)
+		c.flags |= clientCloseAfterReply
+		var perr protocolError
+		if errors.As(m.Err, &perr) {
+			return c.reply(nil, perr)
+		}
+		return c.peer.Close()
+	}
+
+	// Requests are not run while the client is blocked, but queued
+	// until it is served.
+	if c.blocked != nil {
+		c.pending = append(c.pending, m)
+		return nil
 	}
-	res, err := Serialize(x, nil)
-	if err != nil {
-		x, _ := Serialize(err, nil)
-		return m.Peer.Send([]byte(x))
+	r, err := RunCommand(c, m.Payload)
+	if err == errorBlocked {
+		return nil
 	}
-	return m.Peer.Send([]byte(res))
+	err = c.reply(r, err)
+	s.serveBlockedClients()
+	return err
 }
@@ -1,36 +1,105 @@
 package redis
 
-import "testing"
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
 
-func Test_eatBulkString(t *testing.T) {
+func Test_parseRequest(t *testing.T) {
 	type testCase struct {
-		name string
-		data []byte
-		c    int
-		want int
+		name     string
+		data     []byte
+		wantArgs []string
+		wantN    int
+		wantNeed int
+		wantErr  bool
 	}
 	tests := []testCase{
-		// TODO: Add test cases.
-		{"test-1", []byte("$0\r\n\r\n"), 0, 6},
-		{"test-2", []byte("$5\r\nhello\r\n"), 0, 11},
-		{"test-3", []byte("$5\r\nhello\r\nthere"), 0, 11},
-		{"test-4", []byte("$0\r\nhel\r\nthere"), 0, 0},
-		{"test-5", []byte("$5\r\nhelllllo\r\nthere"), 0, 0},
-		{"test-6", []byte("$55\r\nhelllllo\r\nthere"), 0, 0},
-		{"test-7", []byte("$\r\nhelllllo\r\nthere"), 0, 0},
-		{"test-8", []byte("5\r\nhelllllo\r\nthere"), 0, 0},
-		{"test-9", []byte("$a\r\nhello\r\nthere\r\n"), 0, 0},
-		{"test-10", []byte("$5\r\nhellothere"), 0, 0},
-		{"test-11", []byte("random$5\r\nhello\r\n"), 6, 11},
-		{"test-12", []byte("random"), 0, 0},
-		{"test-13", []byte("$6\r\nrandom"), 0, 0},
-		{"test-14", []byte("$6\r\nrando\r\n"), 0, 0},
+		{"test-1", []byte("*1\r\n$0\r\n\r\n"), []string{""}, 10, 0, false},
+		{"test-2", []byte("*1\r\n$5\r\nhello\r\n"), []string{"hello"}, 15, 0, false},
+		{"test-3", []byte("*1\r\n$5\r\nhello\r\n*1\r\n$5\r\nthere\r\n"), []string{"hello"}, 15, 0, false},
+		{"test-4", []byte("*1\r\n$0\r\nhel\r\nthere"), nil, 0, 0, true},
+		{"test-5", []byte("*1\r\n$5\r\nhelllllo\r\nthere"), nil, 0, 0, true},
+		{"test-6", []byte("*1\r\n$55\r\nhelllllo\r\nthere"), nil, 0, 42, false},
+		{"test-7", []byte("*1\r\n$\r\nhelllllo\r\nthere"), nil, 0, 0, true},
+		{"test-8", []byte("*1\r\n5\r\nhelllllo\r\nthere"), nil, 0, 0, true},
+		{"test-9", []byte("*1\r\n$a\r\nhello\r\nthere\r\n"), nil, 0, 0, true},
+		{"test-10", []byte("*1\r\n$5\r\nhellothere"), nil, 0, 0, true},
+		{"test-11", []byte("*2\r\n$3\r\nGET\r\n$5\r\nhe"), nil, 0, 5, false},
+		{"test-12", []byte("*2\r\n$3\r\nGET\r\n$5"), nil, 0, 1, false},
+		{"test-13", []byte("*2\r\n$3\r\nGET\r\n"), nil, 0, 1, false},
+		{"test-14", []byte("*2\r"), nil, 0, 1, false},
+		{"test-15", []byte("*2\r\n$3\r\nSET\r\n$4\r\na\r\nb\r\n"), []string{"SET", "a\r\nb"}, 23, 0, false},
+		{"test-16", []byte("*1\r\n$3\r\n\x00\xff\n\r\n"), []string{"\x00\xff\n"}, 13, 0, false},
+		{"test-17", []byte("*0\r\n"), nil, 4, 0, false},
+		{"test-18", []byte("*-1\r\n"), nil, 5, 0, false},
+		{"test-19", []byte("*x\r\n"), nil, 0, 0, true},
+		{"test-20", []byte("*1048577\r\n"), nil, 0, 0, true},
+		{"test-21", []byte("*1\r\n$-1\r\n"), nil, 0, 0, true},
+		{"test-22", []byte("*1\r\n$536870913\r\n"), nil, 0, 0, true},
+		{"test-23", []byte("PING\r\n"), []string{"PING"}, 6, 0, false},
+		{"test-24", []byte("ECHO hello\nPING\r\n"), []string{"ECHO", "hello"}, 11, 0, false},
+		{"test-25", []byte("PING"), nil, 0, 1, false},
+		{"test-26", []byte("\r\n"), nil, 2, 0, false},
+		{"test-27", []byte(""), nil, 0, 1, false},
+		{"test-29", []byte("SET  greeting \"hello world\"\r\n"), []string{"SET", "greeting", "hello world"}, 29, 0, false},
+		{"test-30", []byte("  SET\tk  'it\\'s'  \r\n"), []string{"SET", "k", "it's"}, 20, 0, false},
+		{"test-31", []byte("SET k \"a\\nb\\x41\\\"\\q\"\n"), []string{"SET", "k", "a\nbA\"q"}, 21, 0, false},
+		{"test-32", []byte("SET k \"\"\n"), []string{"SET", "k", ""}, 9, 0, false},
+		{"test-33", []byte("SET k fo\"o b\"ar\n"), nil, 0, 0, true},
+		{"test-34", []byte("SET k fo\"o b\"\n"), []string{"SET", "k", "foo b"}, 14, 0, false},
+		{"test-35", []byte("SET k \"hello\n"), nil, 0, 0, true},
+		{"test-36", []byte("SET k 'hello\n"), nil, 0, 0, true},
+		{"test-37", []byte("SET k \"a\"b\n"), nil, 0, 0, true},
+		{"test-38", []byte(" \t \r\n"), nil, 5, 0, false},
+		{"test-28", []byte(strings.Repeat("a", maxInlineLen+1)), nil, 0, 0, true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := eatBulkString(tt.data, tt.c); got != tt.want {
-				t.Errorf("eatBulkString() = %v, want %v", got, tt.want)
+			args, n, need, err := parseRequest(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var gotArgs []string
+			for _, a := range args {
+				gotArgs = append(gotArgs, string(a))
+			}
+			if !slices.Equal(gotArgs, tt.wantArgs) || n != tt.wantN || need != tt.wantNeed {
+				t.Errorf("parseRequest() = %q, %v, %v, want %q, %v, %v", gotArgs, n, need, tt.wantArgs, tt.wantN, tt.wantNeed)
 			}
 		})
 	}
 }
+
+func Test_respReceiver(t *testing.T) {
+	large := strings.Repeat("x", 4<<20)
+	want := make([]string, 0)
+	for i := 0; i < 10000; i++ {
+		want = append(want, fmt.Sprintf("*3\r\n$3\r\nSET\r\n$%d\r\nkey-%d\r\n$1\r\n%d\r\n", len(fmt.Sprint("key-", i)), i, i%10))
+	}
+	want = append(want, fmt.Sprintf("*3\r\n$3\r\nSET\r\n$5\r\nlarge\r\n$%d\r\n%s\r\n", len(large), large))
+	want = append(want, "PING\r\n")
+	want = append(want, "*1\r\n$4\r\nPING\r\n")
+
+	// Empty requests in between are skipped.
+	stream := strings.Join(want, "\r\n*0\r\n")
+	// Deliver the stream in small segments, as a TCP connection would.
+	r := newRESPReceiver(bufio.NewReader(iotest.HalfReader(strings.NewReader(stream))))
+	for i, w := range want {
+		got, err := r.Receive()
+		if err != nil {
+			t.Fatalf("Receive() message %d error = %v", i, err)
+		}
+		if string(got) != w {
+			t.Fatalf("Receive() message %d = %.40q, want %.40q", i, got, w)
+		}
+	}
+	if _, err := r.Receive(); err != io.EOF {
+		t.Errorf("Receive() after last message error = %v, want %v", err, io.EOF)
+	}
+}
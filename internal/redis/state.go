@@ -7,10 +7,16 @@ type State struct {
 }
 
 type stateValue struct {
-	val       any
+	val any
+	// expiresAt is the zero time for keys without expiry.
 	expiresAt time.Time
 }
 
+// expired reports whether the value has expired by now.
+func (v *stateValue) expired(now time.Time) bool {
+	return !v.expiresAt.IsZero() && !now.Before(v.expiresAt)
+}
+
 func NewState() State {
 	return State{
 		data: make(map[string]*stateValue),
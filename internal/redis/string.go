@@ -0,0 +1,675 @@
+package redis
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Avik32223/redis-server/internal/rediserr"
+)
+
+var stringCommands = []*commandSpec{
+	{
+		name: "append", run: appendCommand, arity: 3,
+		flags:    []string{flagWrite, flagDenyOOM, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "INSERT"},
+		categories: []string{"@write", "@string", "@fast"},
+		group:      "string", since: "2.0.0", complexity: "O(1). The amortized time complexity is O(1) assuming the appended value is small and the already present value is of any size, since the dynamic string library used by Redis will double the free space available on every reallocation.",
+		summary: "Appends a string to the value of a key. Creates the key if it doesn't exist.",
+	},
+	{
+		name: "strlen", run: strlen, arity: 2,
+		flags:    []string{flagReadonly, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RO"},
+		categories: []string{"@read", "@string", "@fast"},
+		group:      "string", since: "2.2.0", complexity: "O(1)",
+		summary: "Returns the length of a string value.",
+	},
+	{
+		name: "getrange", run: getrange, arity: 4,
+		flags:    []string{flagReadonly},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RO", "ACCESS"},
+		categories: []string{"@read", "@string", "@slow"},
+		group:      "string", since: "2.4.0", complexity: "O(N) where N is the length of the returned string. The complexity is ultimately determined by the returned length, but because creating a substring from an existing string is very cheap, it can be considered O(1) for small strings.",
+		summary: "Returns a substring of the string stored at a key.",
+	},
+	{
+		name: "substr", run: getrange, arity: 4,
+		flags:    []string{flagReadonly},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RO", "ACCESS"},
+		categories: []string{"@read", "@string", "@slow"},
+		group:      "string", since: "1.0.0", complexity: "O(N) where N is the length of the returned string. The complexity is ultimately determined by the returned length, but because creating a substring from an existing string is very cheap, it can be considered O(1) for small strings.",
+		summary: "Returns a substring from a string value.",
+	},
+	{
+		name: "setrange", run: setrange, arity: 4,
+		flags:    []string{flagWrite, flagDenyOOM},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "UPDATE"},
+		categories: []string{"@write", "@string", "@slow"},
+		group:      "string", since: "2.2.0", complexity: "O(1), not counting the time taken to copy the new string in place. Usually, this string is very small so the amortized complexity is O(1). Otherwise, complexity is O(M) with M being the length of the value argument.",
+		summary: "Overwrites a part of a string value with another by an offset. Creates the key if it doesn't exist.",
+	},
+	{
+		name: "mget", run: mget, arity: -2,
+		flags:    []string{flagReadonly, flagFast},
+		firstKey: 1, lastKey: -1, keyStep: 1, keyFlags: []string{"RO", "ACCESS"},
+		categories: []string{"@read", "@string", "@fast"},
+		group:      "string", since: "1.0.0", complexity: "O(N) where N is the number of keys to retrieve.",
+		summary: "Atomically returns the string values of one or more keys.",
+	},
+	{
+		name: "mset", run: mset, arity: -3,
+		flags:    []string{flagWrite, flagDenyOOM},
+		firstKey: 1, lastKey: -1, keyStep: 2, keyFlags: []string{"OW", "UPDATE"},
+		categories: []string{"@write", "@string", "@slow"},
+		group:      "string", since: "1.0.1", complexity: "O(N) where N is the number of keys to set.",
+		summary: "Atomically creates or modifies the string values of one or more keys.",
+	},
+	{
+		name: "msetnx", run: msetnx, arity: -3,
+		flags:    []string{flagWrite, flagDenyOOM},
+		firstKey: 1, lastKey: -1, keyStep: 2, keyFlags: []string{"OW", "INSERT"},
+		categories: []string{"@write", "@string", "@slow"},
+		group:      "string", since: "1.0.1", complexity: "O(N) where N is the number of keys to set.",
+		summary: "Atomically modifies the string values of one or more keys only when all keys don't exist.",
+	},
+	{
+		name: "getex", run: getex, arity: -2,
+		flags:    []string{flagWrite, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "UPDATE"},
+		categories: []string{"@write", "@string", "@fast"},
+		group:      "string", since: "6.2.0", complexity: "O(1)",
+		summary: "Returns the string value of a key after setting its expiration time.",
+	},
+	{
+		name: "getdel", run: getdel, arity: 2,
+		flags:    []string{flagWrite, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "DELETE"},
+		categories: []string{"@write", "@string", "@fast"},
+		group:      "string", since: "6.2.0", complexity: "O(1)",
+		summary: "Returns the string value of a key after deleting the key.",
+	},
+	{
+		name: "getset", run: getset, arity: 3,
+		flags:    []string{flagWrite, flagDenyOOM, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "UPDATE"},
+		categories: []string{"@write", "@string", "@fast"},
+		group:      "string", since: "1.0.0", complexity: "O(1)",
+		summary: "Returns the previous string value of a key after setting it to a new value.",
+	},
+	{
+		name: "incrby", run: incrby, arity: 3,
+		flags:    []string{flagWrite, flagDenyOOM, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "UPDATE"},
+		categories: []string{"@write", "@string", "@fast"},
+		group:      "string", since: "1.0.0", complexity: "O(1)",
+		summary: "Increments the integer value of a key by a number. Uses 0 as initial value if the key doesn't exist.",
+	},
+	{
+		name: "decrby", run: decrby, arity: 3,
+		flags:    []string{flagWrite, flagDenyOOM, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "UPDATE"},
+		categories: []string{"@write", "@string", "@fast"},
+		group:      "string", since: "1.0.0", complexity: "O(1)",
+		summary: "Decrements a number from the integer value of a key. Uses 0 as initial value if the key doesn't exist.",
+	},
+	{
+		name: "incrbyfloat", run: incrbyfloat, arity: 3,
+		flags:    []string{flagWrite, flagDenyOOM, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RW", "ACCESS", "UPDATE"},
+		categories: []string{"@write", "@string", "@fast"},
+		group:      "string", since: "2.6.0", complexity: "O(1)",
+		summary: "Increment the floating point value of a key by a number. Uses 0 as initial value if the key doesn't exist.",
+	},
+	{
+		name: "delex", run: delex, arity: -2,
+		flags:    []string{flagWrite, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RM", "DELETE"},
+		categories: []string{"@write", "@string", "@fast"},
+		group:      "string", since: "8.4.0", complexity: "O(1) for IFEQ/IFNE, O(N) for IFDEQ/IFDNE where N is the length of the string value.",
+		summary: "Conditionally removes the specified key based on value or digest comparison.",
+	},
+	{
+		name: "digest", run: digest, arity: 2,
+		flags:    []string{flagReadonly, flagFast},
+		firstKey: 1, lastKey: 1, keyStep: 1, keyFlags: []string{"RO", "ACCESS"},
+		categories: []string{"@read", "@string", "@fast"},
+		group:      "string", since: "8.4.0", complexity: "O(N) where N is the length of the string value.",
+		summary: "Returns the hash digest of a string value as a hexadecimal string.",
+	},
+	{
+		name: "lcs", run: lcs, arity: -3,
+		flags:    []string{flagReadonly},
+		firstKey: 1, lastKey: 2, keyStep: 1, keyFlags: []string{"RO", "ACCESS"},
+		categories: []string{"@read", "@string", "@slow"},
+		group:      "string", since: "7.0.0", complexity: "O(N*M) where N and M are the lengths of s1 and s2, respectively",
+		summary: "Finds the longest common substring.",
+	},
+}
+
+var errorStringTooLong = rediserr.New(rediserr.ERR, "string exceeds maximum allowed size (proto-max-bulk-len)")
+
+// lookupString returns the string stored at key, and whether key exists.
+func lookupString(s State, key string) (string, bool, error) {
+	v, err := lookup(s, key)
+	if err != nil {
+		return "", false, nil
+	}
+	vt, ok := v.(string)
+	if !ok {
+		return "", true, rediserr.WrongType
+	}
+	return vt, true, nil
+}
+
+// setString stores v at key, keeping the expiry key may have.
+func setString(s State, key string, v string) {
+	data := *s.Data()
+	if x, ok := data[key]; ok {
+		x.val = v
+		return
+	}
+	data[key] = &stateValue{val: v}
+}
+
+func appendCommand(c *Client, ca ...any) (Reply, error) {
+	s := c.db()
+	key, suffix := ca[0].(string), ca[1].(string)
+	v, _, err := lookupString(s, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(v)+len(suffix) > maxBulkLen {
+		return nil, errorStringTooLong
+	}
+	v += suffix
+	setString(s, key, v)
+	return IntegerReply(len(v)), nil
+}
+
+func strlen(c *Client, ca ...any) (Reply, error) {
+	v, _, err := lookupString(c.db(), ca[0].(string))
+	if err != nil {
+		return nil, err
+	}
+	return IntegerReply(len(v)), nil
+}
+
+// getrange replies with the bytes of a string from start to end included.
+// Negative offsets count from the end of the string, -1 being its last byte.
+func getrange(c *Client, ca ...any) (Reply, error) {
+	start, err := parseInt(ca[1].(string))
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseInt(ca[2].(string))
+	if err != nil {
+		return nil, err
+	}
+	v, _, err := lookupString(c.db(), ca[0].(string))
+	if err != nil {
+		return nil, err
+	}
+
+	if start < 0 && end < 0 && start > end {
+		return BulkReply(""), nil
+	}
+	l := int64(len(v))
+	if start < 0 {
+		start = max(l+start, 0)
+	}
+	if end < 0 {
+		end = max(l+end, 0)
+	}
+	end = min(end, l-1)
+	if start > end || l == 0 {
+		return BulkReply(""), nil
+	}
+	return BulkReply(v[start : end+1]), nil
+}
+
+// setrange overwrites a string from offset on, padding it with zero bytes
+// if it is shorter than offset.
+func setrange(c *Client, ca ...any) (Reply, error) {
+	s := c.db()
+	key, value := ca[0].(string), ca[2].(string)
+	offset, err := parseInt(ca[1].(string))
+	if err != nil {
+		return nil, err
+	}
+	if offset < 0 {
+		return nil, rediserr.New(rediserr.ERR, "offset is out of range")
+	}
+	v, _, err := lookupString(s, key)
+	if err != nil {
+		return nil, err
+	}
+	// Nothing is written, nor created, for an empty value.
+	if len(value) == 0 {
+		return IntegerReply(len(v)), nil
+	}
+	if offset+int64(len(value)) > maxBulkLen {
+		return nil, errorStringTooLong
+	}
+
+	b := []byte(v)
+	if end := int(offset) + len(value); end > len(b) {
+		b = append(b, make([]byte, end-len(b))...)
+	}
+	copy(b[offset:], value)
+	setString(s, key, string(b))
+	return IntegerReply(len(b)), nil
+}
+
+// mget replies with the value of every key, or null for the keys that do
+// not exist or do not hold a string.
+func mget(c *Client, ca ...any) (Reply, error) {
+	s := c.db()
+	res := make(ArrayReply, 0, len(ca))
+	for _, key := range ca {
+		v, found, err := lookupString(s, key.(string))
+		if !found || err != nil {
+			res = append(res, NullBulkReply{})
+			continue
+		}
+		res = append(res, BulkReply(v))
+	}
+	return res, nil
+}
+
+// mset sets every key to its value, as SET would: replacing values of any
+// type and removing their expiry.
+func mset(c *Client, ca ...any) (Reply, error) {
+	if len(ca)%2 != 0 {
+		return nil, rediserr.WrongArity("mset")
+	}
+	msetPairs(c.db(), ca)
+	return okReply, nil
+}
+
+// msetnx sets every key to its value, unless any of the keys exists,
+// in which case none of them is set.
+func msetnx(c *Client, ca ...any) (Reply, error) {
+	if len(ca)%2 != 0 {
+		return nil, rediserr.WrongArity("msetnx")
+	}
+	s := c.db()
+	for i := 0; i < len(ca); i += 2 {
+		if _, err := lookup(s, ca[i].(string)); err == nil {
+			return IntegerReply(0), nil
+		}
+	}
+	msetPairs(s, ca)
+	return IntegerReply(1), nil
+}
+
+func msetPairs(s State, ca []any) {
+	data := *s.Data()
+	for i := 0; i < len(ca); i += 2 {
+		data[ca[i].(string)] = &stateValue{val: ca[i+1].(string)}
+	}
+}
+
+// getex replies with the value of key, and sets or removes its expiry.
+func getex(c *Client, ca ...any) (Reply, error) {
+	s := c.db()
+	key := ca[0].(string)
+	now := time.Now()
+
+	expiry, expiryValue := "", ""
+	for i := 1; i < len(ca); i++ {
+		opt := strings.ToUpper(ca[i].(string))
+		switch {
+		case opt == "PERSIST" && expiry == "":
+			expiry = opt
+		case (opt == "EX" || opt == "PX" || opt == "EXAT" || opt == "PXAT") && expiry == "" && i+1 < len(ca):
+			expiry, expiryValue = opt, ca[i+1].(string)
+			i++
+		default:
+			return nil, rediserr.Syntax
+		}
+	}
+	// The expiry is checked whether key exists or not.
+	var expiresAt time.Time
+	if expiry != "" && expiry != "PERSIST" {
+		var err error
+		if expiresAt, err = parseExpiry("getex", expiry, expiryValue, now); err != nil {
+			return nil, err
+		}
+	}
+
+	v, found, err := lookupString(s, key)
+	if !found {
+		return NullBulkReply{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data := *s.Data()
+	switch expiry {
+	case "":
+	case "PERSIST":
+		data[key].expiresAt = time.Time{}
+	default:
+		if !expiresAt.After(now) {
+			delete(data, key)
+			break
+		}
+		data[key].expiresAt = expiresAt
+	}
+	return BulkReply(v), nil
+}
+
+func getdel(c *Client, ca ...any) (Reply, error) {
+	s := c.db()
+	key := ca[0].(string)
+	v, found, err := lookupString(s, key)
+	if !found {
+		return NullBulkReply{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	delete(*s.Data(), key)
+	return BulkReply(v), nil
+}
+
+// getset is the legacy form of SET key value GET.
+func getset(c *Client, ca ...any) (Reply, error) {
+	return set(c, ca[0], ca[1], "GET")
+}
+
+func incrby(c *Client, ca ...any) (Reply, error) {
+	by, err := parseInt(ca[1].(string))
+	if err != nil {
+		return nil, err
+	}
+	return incrBy(c.db(), ca[0].(string), by)
+}
+
+func decrby(c *Client, ca ...any) (Reply, error) {
+	by, err := parseInt(ca[1].(string))
+	if err != nil {
+		return nil, err
+	}
+	if by == math.MinInt64 {
+		return nil, rediserr.New(rediserr.ERR, "decrement would overflow")
+	}
+	return incrBy(c.db(), ca[0].(string), -by)
+}
+
+// incrBy adds by to the integer stored at key, 0 if key does not exist.
+// The expiry of key is kept.
+func incrBy(s State, key string, by int64) (Reply, error) {
+	v, found, err := lookupString(s, key)
+	if err != nil {
+		return nil, err
+	}
+	var i int64
+	if found {
+		if i, err = parseInt(v); err != nil {
+			return nil, err
+		}
+	}
+	if (by < 0 && i < 0 && by < math.MinInt64-i) || (by > 0 && i > 0 && by > math.MaxInt64-i) {
+		return nil, rediserr.Overflow
+	}
+	i += by
+	setString(s, key, strconv.FormatInt(i, 10))
+	return IntegerReply(i), nil
+}
+
+// longDoublePrec is the precision of the long double redis computes
+// INCRBYFLOAT with, so increments round, and format, the same way.
+const (
+	longDoublePrec   = 64
+	longDoubleMaxExp = 16384
+)
+
+// parseLongDouble parses s as a float, the way redis parses long doubles.
+func parseLongDouble(s string) (*big.Float, error) {
+	if len(s) == 0 || isSpace(s[0]) {
+		return nil, rediserr.NotFloat
+	}
+	f, _, err := new(big.Float).SetPrec(longDoublePrec).Parse(s, 10)
+	if err != nil || (!f.IsInf() && f.MantExp(nil) > longDoubleMaxExp) {
+		return nil, rediserr.NotFloat
+	}
+	return f, nil
+}
+
+// formatLongDouble formats f with 17 decimals, trailing zeros removed.
+func formatLongDouble(f *big.Float) string {
+	d := f.Text('f', 17)
+	d = strings.TrimRight(d, "0")
+	d = strings.TrimSuffix(d, ".")
+	if d == "-0" {
+		return "0"
+	}
+	return d
+}
+
+func incrbyfloat(c *Client, ca ...any) (Reply, error) {
+	s := c.db()
+	key := ca[0].(string)
+	v, found, err := lookupString(s, key)
+	if err != nil {
+		return nil, err
+	}
+	f := new(big.Float).SetPrec(longDoublePrec)
+	if found {
+		if f, err = parseLongDouble(v); err != nil {
+			return nil, err
+		}
+	}
+	by, err := parseLongDouble(ca[1].(string))
+	if err != nil {
+		return nil, err
+	}
+	if f.IsInf() || by.IsInf() || f.Add(f, by).MantExp(nil) > longDoubleMaxExp {
+		return nil, rediserr.New(rediserr.ERR, "increment would produce NaN or Infinity")
+	}
+	d := formatLongDouble(f)
+	setString(s, key, d)
+	return BulkReply(d), nil
+}
+
+// valueCondition is a condition on the current value of a key, checked
+// before writing it: NX, XX, or a comparison of the value with IFEQ and
+// IFNE, or of its digest with IFDEQ and IFDNE. The zero valueCondition
+// always matches.
+type valueCondition struct {
+	op  string
+	arg string
+}
+
+func isValueCondition(op string) bool {
+	return op == "IFEQ" || op == "IFNE" || op == "IFDEQ" || op == "IFDNE"
+}
+
+// match reports whether v, the string stored at a key if found, meets the condition.
+// Comparisons with IFEQ and IFDEQ fail for missing keys, and succeed with IFNE and IFDNE.
+func (vc valueCondition) match(v string, found bool) bool {
+	switch vc.op {
+	case "NX":
+		return !found
+	case "XX":
+		return found
+	case "IFEQ":
+		return found && v == vc.arg
+	case "IFNE":
+		return !found || v != vc.arg
+	case "IFDEQ":
+		return found && strings.EqualFold(stringDigest(v), vc.arg)
+	case "IFDNE":
+		return !found || !strings.EqualFold(stringDigest(v), vc.arg)
+	}
+	return true
+}
+
+// stringDigest is the digest DIGEST replies with, and IFDEQ and IFDNE
+// compare to: the XXH3 64 bits hash of v, in hexadecimal, as redis digests.
+func stringDigest(v string) string {
+	return fmt.Sprintf("%016x", xxh3([]byte(v)))
+}
+
+// delex deletes key, of any type. With a condition, key must hold a string
+// meeting it to be deleted.
+func delex(c *Client, ca ...any) (Reply, error) {
+	s := c.db()
+	key := ca[0].(string)
+	var cond valueCondition
+	if len(ca) > 1 {
+		op := strings.ToUpper(ca[1].(string))
+		if len(ca) != 3 || !isValueCondition(op) {
+			return nil, rediserr.Syntax
+		}
+		cond = valueCondition{op: op, arg: ca[2].(string)}
+	}
+
+	v, err := lookup(s, key)
+	if err != nil {
+		return IntegerReply(0), nil
+	}
+	vt, ok := v.(string)
+	if !ok && cond.op != "" {
+		return nil, rediserr.WrongType
+	}
+	if !cond.match(vt, true) {
+		return IntegerReply(0), nil
+	}
+	delete(*s.Data(), key)
+	return IntegerReply(1), nil
+}
+
+func digest(c *Client, ca ...any) (Reply, error) {
+	v, found, err := lookupString(c.db(), ca[0].(string))
+	if !found {
+		return NullBulkReply{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return BulkReply(stringDigest(v)), nil
+}
+
+// lcs finds the longest common subsequence of the strings at two keys,
+// missing keys counting as empty strings. It replies with the subsequence,
+// its length with LEN, or with IDX the ranges matching in both strings,
+// from the last to the first, that are at least MINMATCHLEN long.
+func lcs(c *Client, ca ...any) (Reply, error) {
+	s := c.db()
+	a, _, errA := lookupString(s, ca[0].(string))
+	b, _, errB := lookupString(s, ca[1].(string))
+	if errA != nil || errB != nil {
+		return nil, rediserr.New(rediserr.ERR, "The specified keys must contain string values")
+	}
+
+	var getLen, getIdx, withMatchLen bool
+	var minMatchLen int64
+	for i := 2; i < len(ca); i++ {
+		opt := strings.ToUpper(ca[i].(string))
+		switch {
+		case opt == "IDX":
+			getIdx = true
+		case opt == "LEN":
+			getLen = true
+		case opt == "WITHMATCHLEN":
+			withMatchLen = true
+		case opt == "MINMATCHLEN" && i+1 < len(ca):
+			n, err := parseInt(ca[i+1].(string))
+			if err != nil {
+				return nil, rediserr.NotInteger
+			}
+			minMatchLen = max(n, 0)
+			i++
+		default:
+			return nil, rediserr.Syntax
+		}
+	}
+	if getIdx && getLen {
+		return nil, rediserr.New(rediserr.ERR, "If you want both the length and indexes, please just use IDX.")
+	}
+	if (uint64(len(a))+1)*(uint64(len(b))+1)*4 > maxBulkLen {
+		return nil, rediserr.New(rediserr.ERR, "Insufficient memory, transient memory for LCS exceeds proto-max-bulk-len")
+	}
+
+	// table[i*(len(b)+1)+j] is the length of the LCS of a[:i] and b[:j].
+	w := len(b) + 1
+	table := make([]uint32, (len(a)+1)*w)
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				table[i*w+j] = table[(i-1)*w+j-1] + 1
+			} else {
+				table[i*w+j] = max(table[(i-1)*w+j], table[i*w+j-1])
+			}
+		}
+	}
+	n := table[len(a)*w+len(b)]
+	if getLen {
+		return IntegerReply(n), nil
+	}
+
+	// Walk the table back from the end of both strings, collecting the
+	// subsequence and the ranges of contiguous matches along the way.
+	// aStart == len(a) means there is no range being tracked.
+	result := make([]byte, n)
+	matches := ArrayReply{}
+	aStart, aEnd, bStart, bEnd := len(a), 0, 0, 0
+	for i, j, k := len(a), len(b), n; i > 0 && j > 0; {
+		emit := false
+		if a[i-1] == b[j-1] {
+			result[k-1] = a[i-1]
+			if aStart == len(a) {
+				aStart, aEnd, bStart, bEnd = i-1, i-1, j-1, j-1
+			} else if aStart == i && bStart == j {
+				aStart--
+				bStart--
+			} else {
+				emit = true
+			}
+			// Matching the first byte of either string ends the walk.
+			if aStart == 0 || bStart == 0 {
+				emit = true
+			}
+			i, j, k = i-1, j-1, k-1
+		} else {
+			if table[(i-1)*w+j] > table[i*w+j-1] {
+				i--
+			} else {
+				j--
+			}
+			if aStart != len(a) {
+				emit = true
+			}
+		}
+
+		if emit {
+			matchLen := aEnd - aStart + 1
+			if getIdx && int64(matchLen) >= minMatchLen {
+				match := ArrayReply{
+					ArrayReply{IntegerReply(aStart), IntegerReply(aEnd)},
+					ArrayReply{IntegerReply(bStart), IntegerReply(bEnd)},
+				}
+				if withMatchLen {
+					match = append(match, IntegerReply(matchLen))
+				}
+				matches = append(matches, match)
+			}
+			aStart = len(a)
+		}
+	}
+
+	if getIdx {
+		return MapReply{
+			{Key: BulkReply("matches"), Value: matches},
+			{Key: BulkReply("len"), Value: IntegerReply(n)},
+		}, nil
+	}
+	return BulkReply(result), nil
+}
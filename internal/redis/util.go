@@ -0,0 +1,156 @@
+package redis
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Avik32223/redis-server/internal/rediserr"
+)
+
+// parseInt parses s as a 64 bits integer, the way redis parses integer
+// arguments: in base 10, without leading zeros, plus sign or spaces.
+func parseInt(s string) (int64, error) {
+	if len(s) == 0 || len(s) > 20 {
+		return 0, rediserr.NotInteger
+	}
+	if s == "0" {
+		return 0, nil
+	}
+	neg := s[0] == '-'
+	if neg {
+		s = s[1:]
+	}
+	if len(s) == 0 || s[0] < '1' || s[0] > '9' {
+		return 0, rediserr.NotInteger
+	}
+	var v uint64
+	for i := 0; i < len(s); i++ {
+		d := s[i]
+		if d < '0' || d > '9' || v > (math.MaxUint64-uint64(d-'0'))/10 {
+			return 0, rediserr.NotInteger
+		}
+		v = v*10 + uint64(d-'0')
+	}
+	switch {
+	case neg && v <= 1<<63:
+		// 1<<63 converts to math.MinInt64, which negates to itself.
+		return -int64(v), nil
+	case !neg && v <= math.MaxInt64:
+		return int64(v), nil
+	}
+	return 0, rediserr.NotInteger
+}
+
+// parseRange parses s as an integer from lo to hi. Invalid integers, and
+// integers out of range, are reported with msg if it is not empty.
+func parseRange(s string, lo, hi int64, msg string) (int64, error) {
+	n, err := parseInt(s)
+	switch {
+	case err != nil && msg == "":
+		return 0, err
+	case (err != nil || n < lo || n > hi) && msg != "":
+		return 0, rediserr.New(rediserr.ERR, msg)
+	case n < lo || n > hi:
+		return 0, rediserr.Errorf(rediserr.ERR, "value is out of range, value must between %d and %d", lo, hi)
+	}
+	return n, nil
+}
+
+// parseExpiry parses the value of the EX, PX, EXAT or PXAT option of cmd,
+// and returns the time it sets keys to expire at.
+func parseExpiry(cmd, option, value string, now time.Time) (time.Time, error) {
+	ms, err := parseInt(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	invalid := rediserr.Errorf(rediserr.ERR, "invalid expire time in '%s' command", cmd)
+	if ms <= 0 {
+		return time.Time{}, invalid
+	}
+	option = strings.ToUpper(option)
+	if option == "EX" || option == "EXAT" {
+		if ms > math.MaxInt64/1000 {
+			return time.Time{}, invalid
+		}
+		ms *= 1000
+	}
+	if option == "EX" || option == "PX" {
+		if ms > math.MaxInt64-now.UnixMilli() {
+			return time.Time{}, invalid
+		}
+		ms += now.UnixMilli()
+	}
+	return time.UnixMilli(ms), nil
+}
+
+// stringMatch reports whether s matches the glob-style pattern, the way
+// redis matches keys and fields: * matches any characters, ? any single
+// one, [...] one of a set of characters or ranges of them, negated by a
+// leading ^, and \ escapes the character that follows it.
+func stringMatch(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if stringMatch(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			p := pattern[1:]
+			not := len(p) > 0 && p[0] == '^'
+			if not {
+				p = p[1:]
+			}
+			match := false
+			for len(p) > 0 && p[0] != ']' {
+				switch {
+				case p[0] == '\\' && len(p) > 1:
+					p = p[1:]
+					match = match || p[0] == s[0]
+				case len(p) > 2 && p[1] == '-':
+					lo, hi := min(p[0], p[2]), max(p[0], p[2])
+					match = match || (s[0] >= lo && s[0] <= hi)
+					p = p[2:]
+				default:
+					match = match || p[0] == s[0]
+				}
+				p = p[1:]
+			}
+			if match == not {
+				return false
+			}
+			// An unterminated set ends with the pattern.
+			if len(p) == 0 {
+				return len(s) == 1
+			}
+			pattern = p
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+		}
+		pattern, s = pattern[1:], s[1:]
+	}
+	return len(s) == 0
+}
@@ -0,0 +1,169 @@
+package redis
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// xxh3 returns the XXH3 64 bits hash of b, with the default secret and a
+// seed of 0: the hash redis digests values with.
+// See https://github.com/Cyan4973/xxHash/blob/dev/doc/xxhash_spec.md.
+func xxh3(b []byte) uint64 {
+	n := len(b)
+	switch {
+	case n == 0:
+		return xxh64Avalanche(read64(xxh3Secret[56:]) ^ read64(xxh3Secret[64:]))
+	case n <= 3:
+		c := uint32(b[0])<<16 | uint32(b[n>>1])<<24 | uint32(b[n-1]) | uint32(n)<<8
+		flip := uint64(read32(xxh3Secret[:]) ^ read32(xxh3Secret[4:]))
+		return xxh64Avalanche(uint64(c) ^ flip)
+	case n <= 8:
+		in := uint64(read32(b[n-4:])) + uint64(read32(b))<<32
+		flip := read64(xxh3Secret[8:]) ^ read64(xxh3Secret[16:])
+		return xxh3RRMXMX(in^flip, n)
+	case n <= 16:
+		lo := read64(b) ^ (read64(xxh3Secret[24:]) ^ read64(xxh3Secret[32:]))
+		hi := read64(b[n-8:]) ^ (read64(xxh3Secret[40:]) ^ read64(xxh3Secret[48:]))
+		acc := uint64(n) + bits.ReverseBytes64(lo) + hi + mulFold64(lo, hi)
+		return xxh3Avalanche(acc)
+	case n <= 128:
+		acc := uint64(n) * xxhPrime64_1
+		if n > 32 {
+			if n > 64 {
+				if n > 96 {
+					acc += xxh3Mix16(b[48:], xxh3Secret[96:])
+					acc += xxh3Mix16(b[n-64:], xxh3Secret[112:])
+				}
+				acc += xxh3Mix16(b[32:], xxh3Secret[64:])
+				acc += xxh3Mix16(b[n-48:], xxh3Secret[80:])
+			}
+			acc += xxh3Mix16(b[16:], xxh3Secret[32:])
+			acc += xxh3Mix16(b[n-32:], xxh3Secret[48:])
+		}
+		acc += xxh3Mix16(b, xxh3Secret[:])
+		acc += xxh3Mix16(b[n-16:], xxh3Secret[16:])
+		return xxh3Avalanche(acc)
+	case n <= 240:
+		acc := uint64(n) * xxhPrime64_1
+		for i := 0; i < 8; i++ {
+			acc += xxh3Mix16(b[16*i:], xxh3Secret[16*i:])
+		}
+		acc = xxh3Avalanche(acc)
+		for i := 8; i < n/16; i++ {
+			acc += xxh3Mix16(b[16*i:], xxh3Secret[16*(i-8)+3:])
+		}
+		acc += xxh3Mix16(b[n-16:], xxh3Secret[136-17:])
+		return xxh3Avalanche(acc)
+	}
+	return xxh3Long(b)
+}
+
+const (
+	xxhPrime32_1 = 0x9E3779B1
+	xxhPrime32_2 = 0x85EBCA77
+	xxhPrime32_3 = 0xC2B2AE3D
+	xxhPrime64_1 = 0x9E3779B185EBCA87
+	xxhPrime64_2 = 0xC2B2AE3D27D4EB4F
+	xxhPrime64_3 = 0x165667B19E3779F9
+	xxhPrime64_4 = 0x85EBCA77C2B2AE63
+	xxhPrime64_5 = 0x27D4EB2F165667C5
+)
+
+var xxh3Secret = [192]byte{
+	0xb8, 0xfe, 0x6c, 0x39, 0x23, 0xa4, 0x4b, 0xbe, 0x7c, 0x01, 0x81, 0x2c, 0xf7, 0x21, 0xad, 0x1c,
+	0xde, 0xd4, 0x6d, 0xe9, 0x83, 0x90, 0x97, 0xdb, 0x72, 0x40, 0xa4, 0xa4, 0xb7, 0xb3, 0x67, 0x1f,
+	0xcb, 0x79, 0xe6, 0x4e, 0xcc, 0xc0, 0xe5, 0x78, 0x82, 0x5a, 0xd0, 0x7d, 0xcc, 0xff, 0x72, 0x21,
+	0xb8, 0x08, 0x46, 0x74, 0xf7, 0x43, 0x24, 0x8e, 0xe0, 0x35, 0x90, 0xe6, 0x81, 0x3a, 0x26, 0x4c,
+	0x3c, 0x28, 0x52, 0xbb, 0x91, 0xc3, 0x00, 0xcb, 0x88, 0xd0, 0x65, 0x8b, 0x1b, 0x53, 0x2e, 0xa3,
+	0x71, 0x64, 0x48, 0x97, 0xa2, 0x0d, 0xf9, 0x4e, 0x38, 0x19, 0xef, 0x46, 0xa9, 0xde, 0xac, 0xd8,
+	0xa8, 0xfa, 0x76, 0x3f, 0xe3, 0x9c, 0x34, 0x3f, 0xf9, 0xdc, 0xbb, 0xc7, 0xc7, 0x0b, 0x4f, 0x1d,
+	0x8a, 0x51, 0xe0, 0x4b, 0xcd, 0xb4, 0x59, 0x31, 0xc8, 0x9f, 0x7e, 0xc9, 0xd9, 0x78, 0x73, 0x64,
+	0xea, 0xc5, 0xac, 0x83, 0x34, 0xd3, 0xeb, 0xc3, 0xc5, 0x81, 0xa0, 0xff, 0xfa, 0x13, 0x63, 0xeb,
+	0x17, 0x0d, 0xdd, 0x51, 0xb7, 0xf0, 0xda, 0x49, 0xd3, 0x16, 0x55, 0x26, 0x29, 0xd4, 0x68, 0x9e,
+	0x2b, 0x16, 0xbe, 0x58, 0x7d, 0x47, 0xa1, 0xfc, 0x8f, 0xf8, 0xb8, 0xd1, 0x7a, 0xd0, 0x31, 0xce,
+	0x45, 0xcb, 0x3a, 0x8f, 0x95, 0x16, 0x04, 0x28, 0xaf, 0xd7, 0xfb, 0xca, 0xbb, 0x4b, 0x40, 0x7e,
+}
+
+func read32(b []byte) uint32 { return binary.LittleEndian.Uint32(b) }
+func read64(b []byte) uint64 { return binary.LittleEndian.Uint64(b) }
+
+// mulFold64 multiplies a and b into 128 bits, and folds them into 64.
+func mulFold64(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return hi ^ lo
+}
+
+func xxh64Avalanche(h uint64) uint64 {
+	h ^= h >> 33
+	h *= xxhPrime64_2
+	h ^= h >> 29
+	h *= xxhPrime64_3
+	return h ^ h>>32
+}
+
+func xxh3Avalanche(h uint64) uint64 {
+	h ^= h >> 37
+	h *= 0x165667919E3779F9
+	return h ^ h>>32
+}
+
+func xxh3RRMXMX(h uint64, n int) uint64 {
+	h ^= bits.RotateLeft64(h, 49) ^ bits.RotateLeft64(h, 24)
+	h *= 0x9FB21C651E98DF25
+	h ^= h>>35 + uint64(n)
+	h *= 0x9FB21C651E98DF25
+	return h ^ h>>28
+}
+
+func xxh3Mix16(b, secret []byte) uint64 {
+	return mulFold64(read64(b)^read64(secret), read64(b[8:])^read64(secret[8:]))
+}
+
+// xxh3Long hashes inputs of more than 240 bytes, in blocks of stripes of
+// 64 bytes, each stripe mixed with the secret from a different offset.
+func xxh3Long(b []byte) uint64 {
+	const (
+		stripeLen       = 64
+		stripesPerBlock = (len(xxh3Secret) - stripeLen) / 8
+		blockLen        = stripeLen * stripesPerBlock
+	)
+	acc := [8]uint64{
+		xxhPrime32_3, xxhPrime64_1, xxhPrime64_2, xxhPrime64_3,
+		xxhPrime64_4, xxhPrime32_2, xxhPrime64_5, xxhPrime32_1,
+	}
+	n := len(b)
+	blocks := (n - 1) / blockLen
+	for i := 0; i < blocks; i++ {
+		block := b[i*blockLen:]
+		for s := 0; s < stripesPerBlock; s++ {
+			xxh3Accumulate(&acc, block[s*stripeLen:], xxh3Secret[s*8:])
+		}
+		// Scramble the accumulators.
+		for j := range acc {
+			a := acc[j]
+			a ^= a >> 47
+			a ^= read64(xxh3Secret[len(xxh3Secret)-stripeLen+8*j:])
+			acc[j] = a * xxhPrime32_1
+		}
+	}
+	last := b[blocks*blockLen:]
+	for s := 0; s < (n-1-blocks*blockLen)/stripeLen; s++ {
+		xxh3Accumulate(&acc, last[s*stripeLen:], xxh3Secret[s*8:])
+	}
+	xxh3Accumulate(&acc, b[n-stripeLen:], xxh3Secret[len(xxh3Secret)-stripeLen-7:])
+
+	h := uint64(n) * xxhPrime64_1
+	for i := 0; i < 4; i++ {
+		h += mulFold64(acc[2*i]^read64(xxh3Secret[11+16*i:]), acc[2*i+1]^read64(xxh3Secret[11+16*i+8:]))
+	}
+	return xxh3Avalanche(h)
+}
+
+func xxh3Accumulate(acc *[8]uint64, stripe, secret []byte) {
+	for i := 0; i < 8; i++ {
+		v := read64(stripe[8*i:])
+		k := v ^ read64(secret[8*i:])
+		acc[i^1] += v
+		acc[i] += uint64(uint32(k)) * (k >> 32)
+	}
+}
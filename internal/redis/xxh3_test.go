@@ -0,0 +1,25 @@
+package redis
+
+import "testing"
+
+func Test_xxh3(t *testing.T) {
+	// Inputs of every length the hash tells apart, against the hashes the
+	// reference implementation computes for them.
+	buf := make([]byte, 2100)
+	for i := range buf {
+		buf[i] = byte(i*31 + 7)
+	}
+	tests := []struct {
+		n    int
+		want uint64
+	}{
+		{0, 0x2d06800538d394c2}, {2, 0xa7e250c97710ff27}, {6, 0x99b2e675fba1e0b5},
+		{12, 0x46aaf92c7550afa4}, {100, 0x8c97158042fbf926}, {200, 0x12fdb864685f344d},
+		{1000, 0x989765d0ea7a5ecd}, {2100, 0xa2c1945e463bdc29},
+	}
+	for _, tt := range tests {
+		if got := xxh3(buf[:tt.n]); got != tt.want {
+			t.Errorf("xxh3(%d bytes) = %016x, want %016x", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Package rediserr defines the errors replied to redis clients.
+// Every error starts with a code, such as ERR or WRONGTYPE, that client
+// libraries rely on to tell errors apart.
+package rediserr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Code is the first word of an error reply.
+type Code string
+
+const (
+	ERR         Code = "ERR"
+	WRONGTYPE   Code = "WRONGTYPE"
+	NOAUTH      Code = "NOAUTH"
+	WRONGPASS   Code = "WRONGPASS"
+	NOPERM      Code = "NOPERM"
+	NOPROTO     Code = "NOPROTO"
+	NOSCRIPT    Code = "NOSCRIPT"
+	BUSY        Code = "BUSY"
+	BUSYKEY     Code = "BUSYKEY"
+	LOADING     Code = "LOADING"
+	READONLY    Code = "READONLY"
+	OOM         Code = "OOM"
+	EXECABORT   Code = "EXECABORT"
+	MOVED       Code = "MOVED"
+	ASK         Code = "ASK"
+	TRYAGAIN    Code = "TRYAGAIN"
+	CROSSSLOT   Code = "CROSSSLOT"
+	CLUSTERDOWN Code = "CLUSTERDOWN"
+	NOREPLICAS  Code = "NOREPLICAS"
+	MASTERDOWN  Code = "MASTERDOWN"
+)
+
+// Error is an error replied to a client. Errors are comparable,
+// so predefined ones can be checked for with == or errors.Is.
+type Error struct {
+	Code Code
+	Msg  string
+}
+
+func New(code Code, msg string) Error {
+	return Error{Code: code, Msg: msg}
+}
+
+func Errorf(code Code, format string, a ...any) Error {
+	return New(code, fmt.Sprintf(format, a...))
+}
+
+func (e Error) Error() string {
+	return string(e.Code) + " " + e.Msg
+}
+
+var (
+	Syntax       = New(ERR, "syntax error")
+	WrongType    = New(WRONGTYPE, "Operation against a key holding the wrong kind of value")
+	NotInteger   = New(ERR, "value is not an integer or out of range")
+	NotFloat     = New(ERR, "value is not a valid float")
+	Overflow     = New(ERR, "increment or decrement would overflow")
+	NoSuchKey    = New(ERR, "no such key")
+	OutOfRange   = New(ERR, "index out of range")
+	NoAuth       = New(NOAUTH, "Authentication required.")
+	WrongPass    = New(WRONGPASS, "invalid username-password pair or user is disabled.")
+	NoProto      = New(NOPROTO, "unsupported protocol version")
+	NoScript     = New(NOSCRIPT, "No matching script. Please use EVAL.")
+	Busy         = New(BUSY, "Redis is busy running a script. You can only call SCRIPT KILL or SHUTDOWN NOSAVE.")
+	BusyKey      = New(BUSYKEY, "Target key name already exists.")
+	Loading      = New(LOADING, "Redis is loading the dataset in memory")
+	ReadOnly     = New(READONLY, "You can't write against a read only replica.")
+	OutOfMemory  = New(OOM, "command not allowed when used memory > 'maxmemory'.")
+	ExecAbort    = New(EXECABORT, "Transaction discarded because of previous errors.")
+	CrossSlot    = New(CROSSSLOT, "Keys in request don't hash to the same slot")
+	ClusterDown  = New(CLUSTERDOWN, "The cluster is down")
+	TryAgain     = New(TRYAGAIN, "Multiple keys request during rehashing of slot")
+	NoReplicas   = New(NOREPLICAS, "Not enough good replicas to write.")
+	MasterDown   = New(MASTERDOWN, "Link with MASTER is down and replica-serve-stale-data is set to 'no'.")
+	NoPermission = New(NOPERM, "this user has no permissions to run this command")
+)
+
+// UnknownCommand is replied to a command that does not exist,
+// quoting its first arguments.
+func UnknownCommand(name string, args []string) Error {
+	b := new(strings.Builder)
+	for _, a := range args {
+		if b.Len() >= 128 {
+			break
+		}
+		fmt.Fprintf(b, "'%s' ", truncate(a, 128-b.Len()))
+	}
+	return Errorf(ERR, "unknown command '%s', with args beginning with: %s", truncate(name, 128), b)
+}
+
+// UnknownSubcommand is replied to a subcommand of name that does not exist.
+func UnknownSubcommand(name, sub string) Error {
+	return Errorf(ERR, "unknown subcommand '%s'. Try %s HELP.", truncate(sub, 128), strings.ToUpper(name))
+}
+
+// WrongArity is replied to a command sent with too many or too few arguments.
+// name is the lowercase name of the command, as "get" or "command|info".
+func WrongArity(name string) Error {
+	return Errorf(ERR, "wrong number of arguments for '%s' command", name)
+}
+
+// Moved redirects a client to the node now serving slot.
+func Moved(slot int, addr string) Error {
+	return Errorf(MOVED, "%d %s", slot, addr)
+}
+
+// Ask redirects a client to the node slot is being migrated to, for the next command only.
+func Ask(slot int, addr string) Error {
+	return Errorf(ASK, "%d %s", slot, addr)
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
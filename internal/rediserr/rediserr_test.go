@@ -0,0 +1,32 @@
+package rediserr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{WrongType, "WRONGTYPE Operation against a key holding the wrong kind of value"},
+		{Errorf(ERR, "invalid expire time in '%s' command", "set"), "ERR invalid expire time in 'set' command"},
+		{UnknownCommand("foo", []string{"a", "b"}), "ERR unknown command 'foo', with args beginning with: 'a' 'b' "},
+		{UnknownSubcommand("client", "foo"), "ERR unknown subcommand 'foo'. Try CLIENT HELP."},
+		{WrongArity("command|info"), "ERR wrong number of arguments for 'command|info' command"},
+		{Moved(3999, "127.0.0.1:6381"), "MOVED 3999 127.0.0.1:6381"},
+		{Ask(3999, "127.0.0.1:6381"), "ASK 3999 127.0.0.1:6381"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+
+	wrapped := fmt.Errorf("lookup: %w", WrongType)
+	if !errors.Is(wrapped, WrongType) || errors.Is(wrapped, Syntax) {
+		t.Errorf("errors.Is does not match errors by code and message")
+	}
+}
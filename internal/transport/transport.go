@@ -1,13 +1,20 @@
 package transport
 
+import "net"
+
 type Message struct {
 	Peer    Peer
 	Payload []byte
+
+	// Err is set on the last message of a peer, once receiving from it
+	// failed. The consumer is then responsible for closing the peer.
+	Err error
 }
 
 type Peer interface {
 	Close() error
 	Send([]byte) error
+	RemoteAddr() net.Addr
 }
 
 type Transport interface {
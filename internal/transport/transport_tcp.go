@@ -2,13 +2,19 @@ package transport
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
-	"io"
 	"net"
 )
 
 type TCPPeer struct {
 	net.Conn
+
+	// reader buffers everything read from Conn for the lifetime of the
+	// connection, so bytes following a message are never lost.
+	reader   *bufio.Reader
+	receiver Receiver
 }
 
 func (t *TCPPeer) Close() error {
@@ -24,23 +30,33 @@ func (t *TCPPeer) Send(b []byte) error {
 	return err
 }
 
-type Receiver func(io.Reader) ([]byte, error)
+// Receiver is a stateful decoder bound to a single connection.
+// Each call to Receive returns the next complete message in the order it
+// was sent, keeping any bytes buffered beyond it for the following calls.
+type Receiver interface {
+	Receive() ([]byte, error)
+}
 
-func DefaultReceiver(c io.Reader) ([]byte, error) {
-	scanner := bufio.NewScanner(c)
-	scanner.Split(bufio.ScanLines)
+// ReceiverFunc creates the Receiver used for the lifetime of a connection.
+type ReceiverFunc func(*bufio.Reader) Receiver
 
-	if scanner.Scan() {
-		b := scanner.Bytes()
-		return b, nil
-	}
+type lineReceiver struct {
+	r *bufio.Reader
+}
 
-	if err := scanner.Err(); err != nil {
-		b := scanner.Bytes()
-		return b, err
+func (l *lineReceiver) Receive() ([]byte, error) {
+	b, err := l.r.ReadBytes('\n')
+	if len(b) == 0 && err != nil {
+		return nil, err
 	}
+	b = bytes.TrimSuffix(b, []byte("\n"))
+	b = bytes.TrimSuffix(b, []byte("\r"))
+	return b, nil
+}
 
-	return nil, io.EOF
+// DefaultReceiver splits the stream into lines.
+func DefaultReceiver(r *bufio.Reader) Receiver {
+	return &lineReceiver{r: r}
 }
 
 // TCPTransport implements Transport
@@ -49,15 +65,15 @@ type TCPTransport struct {
 	listenerAddr string
 	consumeCh    chan Message
 
-	Receive   Receiver
-	Handshake HandshakeFunc
+	NewReceiver ReceiverFunc
+	Handshake   HandshakeFunc
 }
 
 func NewTCPTransport(addr string) *TCPTransport {
 	return &TCPTransport{
 		listenerAddr: addr,
 		consumeCh:    make(chan Message),
-		Receive:      DefaultReceiver,
+		NewReceiver:  DefaultReceiver,
 		Handshake:    NoOpHandshake,
 	}
 }
@@ -89,7 +105,11 @@ func (t *TCPTransport) startListening() {
 	for {
 		conn, err := t.listener.Accept()
 		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
 			fmt.Printf("tcp: error. %s\n", err)
+			continue
 		}
 
 		go t.handleConnection(conn)
@@ -97,22 +117,31 @@ func (t *TCPTransport) startListening() {
 }
 
 func (t *TCPTransport) handleConnection(c net.Conn) {
-	peer := TCPPeer{Conn: c}
-	defer peer.Close()
+	peer := TCPPeer{
+		Conn:   c,
+		reader: bufio.NewReader(c),
+	}
+	peer.receiver = t.NewReceiver(peer.reader)
 	// fmt.Printf("tcp: new connection. %#v \n", peer)
 
 	if err := t.Handshake(&peer); err != nil {
 		peer.Send([]byte(err.Error()))
+		peer.Close()
 		return
 	}
 
 	for {
-		b, err := t.Receive(c)
+		// The receiver reads from a buffered stream, so a failed read cannot
+		// be retried: the connection is either closed or out of sync.
+		// The error is handed over to the consumer, which may still reply
+		// before closing the peer.
+		b, err := peer.receiver.Receive()
 		if err != nil {
-			if err == io.EOF {
-				return
+			t.consumeCh <- Message{
+				Peer: &peer,
+				Err:  err,
 			}
-			continue
+			return
 		}
 		msg := Message{
 			Peer:    &peer,
@@ -0,0 +1,204 @@
+package lists
+
+import (
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+// linkedList is the list this package implemented before quicklists, one
+// element allocated per value, kept to benchmark quicklists against.
+type linkedList struct {
+	root linkedElement
+	len  int
+}
+
+type linkedElement struct {
+	Value      any
+	next, prev *linkedElement
+	list       *linkedList
+}
+
+func newLinkedList() *linkedList {
+	l := new(linkedList)
+	l.root.next, l.root.prev = &l.root, &l.root
+	return l
+}
+
+func (l *linkedList) insert(v any, at *linkedElement) {
+	e := &linkedElement{Value: v, prev: at, next: at.next, list: l}
+	e.prev.next = e
+	e.next.prev = e
+	l.len++
+}
+
+func (l *linkedList) Prepend(v any) { l.insert(v, &l.root) }
+func (l *linkedList) Append(v any)  { l.insert(v, l.root.prev) }
+
+func (l *linkedList) remove(e *linkedElement) any {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	e.next, e.prev, e.list = nil, nil, nil
+	l.len--
+	return e.Value
+}
+
+func (l *linkedList) PopFront() any { return l.remove(l.root.next) }
+
+func (l *linkedList) Index(i int) any {
+	e := l.root.next
+	for ; i > 0; i-- {
+		e = e.next
+	}
+	return e.Value
+}
+
+// benchValues are the values lists are filled with in benchmarks: short
+// strings, like most list elements are.
+var benchValues = func() []string {
+	vs := make([]string, 1024)
+	for i := range vs {
+		vs[i] = "element:" + strconv.Itoa(i)
+	}
+	return vs
+}()
+
+func BenchmarkAppend(b *testing.B) {
+	b.Run("quicklist", func(b *testing.B) {
+		b.ReportAllocs()
+		l := NewList()
+		for i := 0; i < b.N; i++ {
+			l.Append(benchValues[i%len(benchValues)])
+		}
+	})
+	b.Run("linked", func(b *testing.B) {
+		b.ReportAllocs()
+		l := newLinkedList()
+		for i := 0; i < b.N; i++ {
+			l.Append(benchValues[i%len(benchValues)])
+		}
+	})
+}
+
+func BenchmarkPrepend(b *testing.B) {
+	b.Run("quicklist", func(b *testing.B) {
+		b.ReportAllocs()
+		l := NewList()
+		for i := 0; i < b.N; i++ {
+			l.Prepend(benchValues[i%len(benchValues)])
+		}
+	})
+	b.Run("linked", func(b *testing.B) {
+		b.ReportAllocs()
+		l := newLinkedList()
+		for i := 0; i < b.N; i++ {
+			l.Prepend(benchValues[i%len(benchValues)])
+		}
+	})
+}
+
+// BenchmarkQueue pushes to and pops from a list used as a queue that holds
+// a thousand elements.
+func BenchmarkQueue(b *testing.B) {
+	b.Run("quicklist", func(b *testing.B) {
+		l := NewList()
+		for _, v := range benchValues {
+			l.Append(v)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			l.Append(benchValues[i%len(benchValues)])
+			l.PopFront()
+		}
+	})
+	b.Run("linked", func(b *testing.B) {
+		l := newLinkedList()
+		for _, v := range benchValues {
+			l.Append(v)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			l.Append(benchValues[i%len(benchValues)])
+			l.PopFront()
+		}
+	})
+}
+
+// BenchmarkIndex reads the elements of a list of 100k elements, at random
+// looking indexes.
+func BenchmarkIndex(b *testing.B) {
+	const n = 100_000
+	b.Run("quicklist", func(b *testing.B) {
+		l := NewList()
+		for i := 0; i < n; i++ {
+			l.Append(benchValues[i%len(benchValues)])
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			l.Index(i * 7919 % n)
+		}
+	})
+	b.Run("linked", func(b *testing.B) {
+		l := newLinkedList()
+		for i := 0; i < n; i++ {
+			l.Append(benchValues[i%len(benchValues)])
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			l.Index(i * 7919 % n)
+		}
+	})
+}
+
+// BenchmarkMemory reports the heap a list of a million elements takes, per
+// element.
+func BenchmarkMemory(b *testing.B) {
+	const n = 1_000_000
+	heap := func() uint64 {
+		var m runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&m)
+		return m.HeapAlloc
+	}
+	measure := func(b *testing.B, fill func() any) {
+		var used uint64
+		for i := 0; i < b.N; i++ {
+			before := heap()
+			l := fill()
+			used = heap() - before
+			runtime.KeepAlive(l)
+		}
+		b.ReportMetric(float64(used)/n, "B/elem")
+	}
+	b.Run("quicklist", func(b *testing.B) {
+		measure(b, func() any {
+			l := NewList()
+			for i := 0; i < n; i++ {
+				l.Append(benchValues[i%len(benchValues)])
+			}
+			return l
+		})
+	})
+	b.Run("quicklist-compressed", func(b *testing.B) {
+		measure(b, func() any {
+			l := NewListOptions(DefaultNodeSize, 1)
+			for i := 0; i < n; i++ {
+				l.Append(benchValues[i%len(benchValues)])
+			}
+			return l
+		})
+	})
+	b.Run("linked", func(b *testing.B) {
+		measure(b, func() any {
+			l := newLinkedList()
+			for i := 0; i < n; i++ {
+				// Values are copied, as the server stores every
+				// element it is sent apart.
+				l.Append(string([]byte(benchValues[i%len(benchValues)])))
+			}
+			return l
+		})
+	})
+}
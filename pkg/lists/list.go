@@ -1,59 +1,691 @@
+// Package lists implements lists of strings as quicklists: doubly linked
+// lists of nodes, each packing a run of elements in a single byte slice
+// the way redis packs them in listpacks. Nodes that are more than a given
+// depth away from both ends of a list may be kept compressed.
 package lists
 
-type Element struct {
-	Value      any
-	next, prev *Element
-	list       *List
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"slices"
+	"sync"
+)
+
+const (
+	// DefaultNodeSize is the most bytes of packed elements a node holds,
+	// unless it holds a single element larger than that.
+	DefaultNodeSize = 8 * 1024
+	// minCompressSize is the size of the smallest node worth compressing.
+	minCompressSize = 48
+)
+
+// An element is packed as its length, as a uvarint, followed by its bytes,
+// then by the size of both encoded backwards: 7 bits per byte, the last
+// byte first, with the high bit set on every byte but the first one. The
+// elements of a node can so be walked from either end.
+
+// varintSize returns the number of bytes n is encoded in, 7 bits per byte.
+func varintSize(n int) int {
+	s := 1
+	for n >= 0x80 {
+		n >>= 7
+		s++
+	}
+	return s
+}
+
+// entrySize returns the number of bytes v is packed in.
+func entrySize(v string) int {
+	h := varintSize(len(v)) + len(v)
+	return h + varintSize(h)
+}
+
+// putEntry packs v at the start of b.
+func putEntry(b []byte, v string) {
+	h := binary.PutUvarint(b, uint64(len(v)))
+	h += copy(b[h:], v)
+	m := varintSize(h)
+	for j := 0; j < m; j++ {
+		g := byte(h>>(7*j)) & 0x7f
+		if j < m-1 {
+			g |= 0x80
+		}
+		b[h+m-1-j] = g
+	}
+}
+
+// entryLen returns the packed size of the element at off in b.
+func entryLen(b []byte, off int) int {
+	l, h := binary.Uvarint(b[off:])
+	return h + int(l) + varintSize(h+int(l))
+}
+
+// readEntry returns the element at off in b, and its packed size.
+func readEntry(b []byte, off int) (string, int) {
+	l, h := binary.Uvarint(b[off:])
+	v := string(b[off+h : off+h+int(l)])
+	return v, h + int(l) + varintSize(h+int(l))
+}
+
+// prevEntry returns the offset of the element that ends at end in b.
+func prevEntry(b []byte, end int) int {
+	i := end - 1
+	n, shift := int(b[i]&0x7f), 7
+	for b[i]&0x80 != 0 {
+		i--
+		n |= int(b[i]&0x7f) << shift
+		shift += 7
+	}
+	return i - n
+}
+
+// entryOffset returns the offset of the element at pos among the count
+// elements packed in b, walking from the nearest end of b.
+func entryOffset(b []byte, count, pos int) int {
+	if pos <= count/2 {
+		off := 0
+		for ; pos > 0; pos-- {
+			off += entryLen(b, off)
+		}
+		return off
+	}
+	off := len(b)
+	for ; count > pos; count-- {
+		off = prevEntry(b, off)
+	}
+	return off
+}
+
+// node holds count packed elements in buf, from off on. When compressed,
+// buf holds the compressed form of rawSize bytes of packed elements instead.
+// The nodes at the ends of a list keep room to grow in buf: before off to
+// prepend elements to, and after its length to append elements to.
+type node struct {
+	prev, next *node
+	buf        []byte
+	off        int
+	count      int
+	compressed bool
+	rawSize    int
+}
+
+func (n *node) data() []byte {
+	return n.buf[n.off:]
+}
+
+// size returns the number of bytes the elements of n are packed in.
+func (n *node) size() int {
+	if n.compressed {
+		return n.rawSize
+	}
+	return len(n.buf) - n.off
+}
+
+// raw returns the packed elements of n, decompressing a copy of them if
+// it is compressed.
+func (n *node) raw() []byte {
+	if n.compressed {
+		return inflate(n.buf, n.rawSize)
+	}
+	return n.data()
+}
+
+func (n *node) prepend(v string, size int) {
+	if n.off < size {
+		d := n.data()
+		room := max(size, len(d))
+		buf := make([]byte, room+len(d))
+		copy(buf[room:], d)
+		n.buf, n.off = buf, room
+	}
+	n.off -= size
+	putEntry(n.buf[n.off:], v)
+}
+
+func (n *node) append(v string, size int) {
+	if d := n.data(); len(n.buf)+size > cap(n.buf) && n.off >= len(d) {
+		// Reclaim the room elements popped from the front left, rather
+		// than growing past it: no more is copied than is reclaimed.
+		n.buf, n.off = n.buf[:copy(n.buf, d)], 0
+	}
+	end := len(n.buf)
+	n.buf = slices.Grow(n.buf, size)[:end+size]
+	putEntry(n.buf[end:], v)
+}
+
+// cut removes the bytes from from to to of the packed elements of n.
+func (n *node) cut(from, to int) {
+	d := n.data()
+	switch {
+	case from == 0:
+		n.off += to
+	case to == len(d):
+		n.buf = n.buf[:n.off+from]
+	default:
+		copy(d[from:], d[to:])
+		n.buf = n.buf[:len(n.buf)-(to-from)]
+	}
 }
 
+// compact drops the room n keeps to grow, once it is no longer at an end.
+func (n *node) compact() {
+	if !n.compressed && (n.off > 0 || cap(n.buf) > len(n.buf)) {
+		n.buf, n.off = bytes.Clone(n.data()), 0
+	}
+}
+
+// List is a list of strings. The zero List is not usable,
+// lists are created by NewList or NewListOptions.
 type List struct {
-	root *Element
-	len  int
+	head, tail    *node
+	len, nodes    int
+	nodeSize      int
+	compressDepth int
 }
 
+// NewList returns an empty list with nodes of DefaultNodeSize bytes,
+// and compression disabled.
 func NewList() *List {
-	l := new(List)
-	root := new(Element)
-	l.root = root
-	l.root.next = root
-	l.root.prev = root
-	l.len = 0
-	return l
+	return NewListOptions(DefaultNodeSize, 0)
+}
+
+// NewListOptions returns an empty list whose nodes pack up to nodeSize
+// bytes of elements, and which compresses the nodes that are more than
+// compressDepth nodes away from both of its ends. A compressDepth of 0
+// disables compression.
+func NewListOptions(nodeSize, compressDepth int) *List {
+	return &List{nodeSize: nodeSize, compressDepth: compressDepth}
 }
 
 func (l *List) Len() int { return l.len }
 
-func (l *List) insert(e, at *Element) *Element {
-	e.prev = at
-	e.next = at.next
-	e.prev.next = e
-	e.next.prev = e
-	e.list = l
+// insertNode links a new empty node after at, or at the head of l if at is nil.
+func (l *List) insertNode(at *node) *node {
+	n := &node{prev: at}
+	if at == nil {
+		n.next = l.head
+		l.head = n
+	} else {
+		n.next = at.next
+		at.next = n
+	}
+	if n.next == nil {
+		l.tail = n
+	} else {
+		n.next.prev = n
+	}
+	l.nodes++
+	return n
+}
+
+// unlink removes n from the nodes of l.
+func (l *List) unlink(n *node) {
+	if n.prev == nil {
+		l.head = n.next
+	} else {
+		n.prev.next = n.next
+	}
+	if n.next == nil {
+		l.tail = n.prev
+	} else {
+		n.next.prev = n.prev
+	}
+	l.nodes--
+}
+
+// Prepend adds v at the start of l.
+func (l *List) Prepend(v string) {
+	size := entrySize(v)
+	n := l.head
+	if n == nil || n.size()+size > l.nodeSize {
+		if n != nil {
+			n.compact()
+		}
+		n = l.insertNode(nil)
+		defer l.compress(nil)
+	}
+	l.decompress(n)
+	n.prepend(v, size)
+	n.count++
+	l.len++
+}
+
+// Append adds v at the end of l.
+func (l *List) Append(v string) {
+	size := entrySize(v)
+	n := l.tail
+	if n == nil || n.size()+size > l.nodeSize {
+		if n != nil {
+			n.compact()
+		}
+		n = l.insertNode(l.tail)
+		defer l.compress(nil)
+	}
+	l.decompress(n)
+	n.append(v, size)
+	n.count++
+	l.len++
+}
+
+// PopFront removes the first element of l and returns it,
+// or reports false if l is empty.
+func (l *List) PopFront() (string, bool) {
+	n := l.head
+	if n == nil {
+		return "", false
+	}
+	l.decompress(n)
+	v, size := readEntry(n.buf, n.off)
+	l.removeEntry(n, 0, size)
+	return v, true
+}
+
+// PopBack removes the last element of l and returns it,
+// or reports false if l is empty.
+func (l *List) PopBack() (string, bool) {
+	n := l.tail
+	if n == nil {
+		return "", false
+	}
+	l.decompress(n)
+	d := n.data()
+	off := prevEntry(d, len(d))
+	v, size := readEntry(d, off)
+	l.removeEntry(n, off, size)
+	return v, true
+}
+
+// locate returns the node holding the element at index i, and the
+// position of the element in it, walking from the nearest end of l.
+// Negative indexes count from the end, -1 being the last element.
+func (l *List) locate(i int) (*node, int, bool) {
+	if i < 0 {
+		i += l.len
+	}
+	if i < 0 || i >= l.len {
+		return nil, 0, false
+	}
+	if i < l.len/2 {
+		n := l.head
+		for i >= n.count {
+			i -= n.count
+			n = n.next
+		}
+		return n, i, true
+	}
+	j := l.len - 1 - i
+	n := l.tail
+	for j >= n.count {
+		j -= n.count
+		n = n.prev
+	}
+	return n, n.count - 1 - j, true
+}
+
+// Index returns the element at index i, or reports false if i is out of
+// range. Negative indexes count from the end, -1 being the last element.
+func (l *List) Index(i int) (string, bool) {
+	n, pos, ok := l.locate(i)
+	if !ok {
+		return "", false
+	}
+	d := n.raw()
+	v, _ := readEntry(d, entryOffset(d, n.count, pos))
+	return v, true
+}
+
+// Set replaces the element at index i with v, or reports false if i is
+// out of range. Negative indexes count from the end, -1 being the last element.
+func (l *List) Set(i int, v string) bool {
+	n, pos, ok := l.locate(i)
+	if !ok {
+		return false
+	}
+	if i < 0 {
+		i += l.len
+	}
+	l.decompress(n)
+	d := n.data()
+	off := entryOffset(d, n.count, pos)
+	old, size := entryLen(d, off), entrySize(v)
+	if n.size()-old+size > l.nodeSize && n.count > 1 {
+		l.DeleteRange(i, 1)
+		l.Insert(i, v)
+		return true
+	}
+	buf := make([]byte, len(d)-old+size)
+	copy(buf, d[:off])
+	putEntry(buf[off:], v)
+	copy(buf[off+size:], d[off+old:])
+	n.buf, n.off = buf, 0
+	l.compress(n)
+	return true
+}
+
+// Insert adds v at index i, from 0 to the length of l, shifting the
+// elements from i on by one. It reports false if i is out of range.
+func (l *List) Insert(i int, v string) bool {
+	switch {
+	case i < 0 || i > l.len:
+		return false
+	case i == 0:
+		l.Prepend(v)
+		return true
+	case i == l.len:
+		l.Append(v)
+		return true
+	}
+
+	n, pos, _ := l.locate(i)
+	size := entrySize(v)
 	l.len++
-	return e
+	if pos == 0 {
+		// v goes between two nodes: at the end of the previous one,
+		// if it has room for it, or in a node of its own.
+		p := n.prev
+		if p.size()+size > l.nodeSize {
+			p = l.insertNode(p)
+		}
+		l.decompress(p)
+		p.append(v, size)
+		p.count++
+		l.compress(p)
+		return true
+	}
+
+	l.decompress(n)
+	d := n.data()
+	off := entryOffset(d, n.count, pos)
+	if n.size()+size <= l.nodeSize {
+		buf := make([]byte, len(d)+size)
+		copy(buf, d[:off])
+		putEntry(buf[off:], v)
+		copy(buf[off+size:], d[off:])
+		n.buf, n.off = buf, 0
+		n.count++
+		l.compress(n)
+		return true
+	}
+
+	// n is full: the elements from pos on move to a node of their own,
+	// and v is added to whichever of the two has room for it.
+	m := l.insertNode(n)
+	m.buf, m.count = bytes.Clone(d[off:]), n.count-pos
+	n.buf, n.off, n.count = bytes.Clone(d[:off]), 0, pos
+	switch {
+	case n.size()+size <= l.nodeSize:
+		n.append(v, size)
+		n.count++
+	case m.size()+size <= l.nodeSize:
+		m.prepend(v, size)
+		m.count++
+	default:
+		k := l.insertNode(n)
+		k.append(v, size)
+		k.count++
+	}
+	l.compress(n)
+	l.compress(m)
+	return true
 }
 
-func (l *List) Prepend(v any) *Element {
-	e := &Element{
-		Value: v,
+// removeEntry removes the element packed at off, in size bytes, in n.
+func (l *List) removeEntry(n *node, off, size int) {
+	l.decompress(n)
+	n.cut(off, off+size)
+	n.count--
+	l.len--
+	if n.count == 0 {
+		l.unlink(n)
+		l.compress(nil)
+		return
 	}
-	return l.insert(e, l.root)
+	l.compress(n)
 }
 
-func (l *List) Append(v any) *Element {
-	e := &Element{
-		Value: v,
+// DeleteRange removes count elements from index start on,
+// or all the elements from start on if there are less.
+func (l *List) DeleteRange(start, count int) {
+	if start < 0 || start >= l.len {
+		return
+	}
+	count = min(count, l.len-start)
+	for count > 0 {
+		n, pos, _ := l.locate(start)
+		del := min(count, n.count-pos)
+		if del == n.count {
+			l.unlink(n)
+		} else {
+			l.decompress(n)
+			d := n.data()
+			from := entryOffset(d, n.count, pos)
+			to := from
+			for j := 0; j < del; j++ {
+				to += entryLen(d, to)
+			}
+			n.cut(from, to)
+			n.count -= del
+			l.compress(n)
+		}
+		l.len -= del
+		count -= del
 	}
-	return l.insert(e, l.root.prev)
+	l.compress(nil)
 }
 
-func (l *List) ToSlice() []any {
-	res := make([]any, 0)
-	root := l.root
-	head := root.next
-	for head != root {
-		res = append(res, head.Value)
-		head = head.next
+// ToSlice returns the elements of l, in order.
+func (l *List) ToSlice() []string {
+	res := make([]string, 0, l.len)
+	for it := l.Iterator(0, false); it.Next(); {
+		res = append(res, it.Value())
 	}
 	return res
 }
+
+// Iterator walks the elements of a list in either direction. The list must
+// not be modified while it is walked, but through the iterator itself.
+type Iterator struct {
+	l       *List
+	reverse bool
+	started bool
+
+	// n is the node of the current element, and raw its packed elements.
+	// The current element is packed in size bytes at off in raw, at
+	// position pos in n and at index in the list.
+	n     *node
+	raw   []byte
+	off   int
+	size  int
+	pos   int
+	index int
+	value string
+
+	// removed is set once the current element is removed, and unlinked
+	// once its node is removed too, resume being the node to walk on to.
+	removed, unlinked bool
+	resume            *node
+}
+
+// Iterator returns an iterator over the elements of l from index i on,
+// towards the end of l, or towards its start if reverse. Negative indexes
+// count from the end, -1 being the last element. Next moves the iterator
+// to the element at i first.
+func (l *List) Iterator(i int, reverse bool) *Iterator {
+	it := &Iterator{l: l, reverse: reverse}
+	n, pos, ok := l.locate(i)
+	if !ok {
+		return it
+	}
+	if i < 0 {
+		i += l.len
+	}
+	it.n, it.raw, it.pos, it.index = n, n.raw(), pos, i
+	it.off = entryOffset(it.raw, n.count, pos)
+	return it
+}
+
+// Next moves the iterator to the next element,
+// and reports false if there are no more elements.
+func (it *Iterator) Next() bool {
+	if it.n == nil {
+		return false
+	}
+	if it.started && !it.advance() {
+		it.n = nil
+		return false
+	}
+	it.started = true
+	it.value, it.size = readEntry(it.raw, it.off)
+	return true
+}
+
+func (it *Iterator) advance() bool {
+	removed, unlinked := it.removed, it.unlinked
+	it.removed, it.unlinked = false, false
+	if !it.reverse {
+		// The element following a removed one takes its index.
+		if unlinked {
+			return it.enter(it.resume, false)
+		}
+		if !removed {
+			it.off += it.size
+			it.pos++
+			it.index++
+		}
+		if it.pos < it.n.count {
+			return true
+		}
+		return it.enter(it.n.next, false)
+	}
+
+	it.index--
+	if unlinked {
+		return it.enter(it.resume, true)
+	}
+	if it.pos--; it.pos >= 0 {
+		it.off = prevEntry(it.raw, it.off)
+		return true
+	}
+	return it.enter(it.n.prev, true)
+}
+
+// enter moves the iterator to the first element of n, or to its last one.
+func (it *Iterator) enter(n *node, last bool) bool {
+	it.n = n
+	if n == nil {
+		return false
+	}
+	it.raw = n.raw()
+	it.pos, it.off = 0, 0
+	if last {
+		it.pos, it.off = n.count-1, prevEntry(it.raw, len(it.raw))
+	}
+	return true
+}
+
+// Value returns the current element.
+func (it *Iterator) Value() string {
+	return it.value
+}
+
+// Index returns the index of the current element in the list.
+func (it *Iterator) Index() int {
+	return it.index
+}
+
+// Remove removes the current element from the list. Next then moves to
+// the element that followed it, in the direction of the iterator.
+func (it *Iterator) Remove() {
+	n, prev, next := it.n, it.n.prev, it.n.next
+	it.l.removeEntry(n, it.off, it.size)
+	it.removed = true
+	if n.count > 0 {
+		it.raw = n.raw()
+		return
+	}
+	it.unlinked, it.resume = true, next
+	if it.reverse {
+		it.resume = prev
+	}
+}
+
+var (
+	flateWriters = sync.Pool{New: func() any {
+		w, _ := flate.NewWriter(nil, flate.BestSpeed)
+		return w
+	}}
+	flateReaders = sync.Pool{New: func() any {
+		return flate.NewReader(bytes.NewReader(nil))
+	}}
+)
+
+func deflate(b []byte) []byte {
+	var out bytes.Buffer
+	w := flateWriters.Get().(*flate.Writer)
+	w.Reset(&out)
+	w.Write(b)
+	w.Close()
+	flateWriters.Put(w)
+	return out.Bytes()
+}
+
+func inflate(b []byte, size int) []byte {
+	r := flateReaders.Get().(io.ReadCloser)
+	r.(flate.Resetter).Reset(bytes.NewReader(b), nil)
+	out := make([]byte, size)
+	if _, err := io.ReadFull(r, out); err != nil {
+		panic("lists: corrupt compressed node: " + err.Error())
+	}
+	flateReaders.Put(r)
+	return out
+}
+
+// compressNode compresses n, unless it is too small, or compressing it
+// would not make it smaller.
+func (l *List) compressNode(n *node) {
+	if n == nil || n.compressed || n.size() < minCompressSize {
+		return
+	}
+	d := n.data()
+	c := deflate(d)
+	if len(c) >= len(d) {
+		return
+	}
+	n.buf, n.off, n.rawSize, n.compressed = c, 0, len(d), true
+}
+
+func (l *List) decompress(n *node) {
+	if n.compressed {
+		n.buf, n.off, n.compressed = inflate(n.buf, n.rawSize), 0, false
+	}
+}
+
+// compress keeps the nodes within compressDepth nodes of either end of l
+// uncompressed, and compresses the nodes right past them, which may have
+// just moved there. n, if it is past them too, is compressed as well.
+func (l *List) compress(n *node) {
+	if l.compressDepth == 0 || l.head == nil {
+		return
+	}
+	fwd, rev := l.head, l.tail
+	inDepth := false
+	for d := 0; d < l.compressDepth; d++ {
+		l.decompress(fwd)
+		l.decompress(rev)
+		if fwd == n || rev == n {
+			inDepth = true
+		}
+		// Every node is within depth.
+		if fwd == rev || fwd.next == rev {
+			return
+		}
+		fwd, rev = fwd.next, rev.prev
+	}
+	if n != nil && !inDepth {
+		l.compressNode(n)
+	}
+	l.compressNode(fwd)
+	l.compressNode(rev)
+}
@@ -0,0 +1,229 @@
+package lists
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newList(vs ...string) *List {
+	l := NewList()
+	for _, v := range vs {
+		l.Append(v)
+	}
+	return l
+}
+
+// smallList returns a list with nodes small enough for a few elements to
+// span several of them, compressing all but the ends if compressDepth > 0.
+func smallList(compressDepth int, vs ...string) *List {
+	l := NewListOptions(64, compressDepth)
+	for _, v := range vs {
+		l.Append(v)
+	}
+	return l
+}
+
+func values(n int) []string {
+	vs := make([]string, n)
+	for i := range vs {
+		vs[i] = fmt.Sprintf("value-%03d", i)
+	}
+	return vs
+}
+
+func TestList_Index(t *testing.T) {
+	l := newList("a", "b", "c", "d", "e")
+	tests := []struct {
+		i    int
+		want string
+		ok   bool
+	}{
+		{0, "a", true}, {1, "b", true}, {3, "d", true}, {4, "e", true},
+		{-1, "e", true}, {-2, "d", true}, {-5, "a", true},
+		{5, "", false}, {-6, "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := l.Index(tt.i); got != tt.want || ok != tt.ok {
+			t.Errorf("Index(%d) = %q, %v, want %q, %v", tt.i, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestList_pushPop(t *testing.T) {
+	for _, depth := range []int{0, 1} {
+		l := smallList(depth)
+		vs := values(100)
+		for i := len(vs)/2 - 1; i >= 0; i-- {
+			l.Prepend(vs[i])
+		}
+		for _, v := range vs[len(vs)/2:] {
+			l.Append(v)
+		}
+		if got := l.ToSlice(); !reflect.DeepEqual(got, vs) || l.Len() != len(vs) {
+			t.Fatalf("depth %d: after pushes, list = %v (len %d), want %v", depth, got, l.Len(), vs)
+		}
+		for i := 0; i < len(vs)/2; i++ {
+			if v, ok := l.PopFront(); v != vs[i] || !ok {
+				t.Fatalf("depth %d: PopFront() = %q, %v, want %q", depth, v, ok, vs[i])
+			}
+			if v, ok := l.PopBack(); v != vs[len(vs)-1-i] || !ok {
+				t.Fatalf("depth %d: PopBack() = %q, %v, want %q", depth, v, ok, vs[len(vs)-1-i])
+			}
+		}
+		if _, ok := l.PopFront(); ok || l.Len() != 0 || l.head != nil || l.tail != nil || l.nodes != 0 {
+			t.Errorf("depth %d: list not empty after popping everything", depth)
+		}
+	}
+}
+
+func TestList_queue(t *testing.T) {
+	// A queue short enough to fit in a single node must not grow it with
+	// the room popped elements leave.
+	l := newList(values(10)...)
+	for i := 0; i < 100_000; i++ {
+		l.Append(fmt.Sprintf("value-%03d", i%1000))
+		l.PopFront()
+	}
+	if l.head != l.tail || l.Len() != 10 {
+		t.Fatalf("queue of %d elements spans %d nodes", l.Len(), l.nodes)
+	}
+	if c := cap(l.head.buf); c > 1024 {
+		t.Errorf("node of %d bytes has a buffer of %d", len(l.head.data()), c)
+	}
+}
+
+func TestList_edit(t *testing.T) {
+	for _, depth := range []int{0, 1} {
+		vs := values(40)
+		l := smallList(depth, vs...)
+
+		l.Insert(0, "first")
+		l.Insert(l.Len(), "last")
+		l.Insert(20, "middle")
+		l.Insert(21, strings.Repeat("x", 100))
+		want := append([]string{"first"}, vs...)
+		want = append(want[:20], append([]string{"middle", strings.Repeat("x", 100)}, want[20:]...)...)
+		want = append(want, "last")
+		if got := l.ToSlice(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("depth %d: after inserts, list = %v, want %v", depth, got, want)
+		}
+		if l.Insert(-1, "x") || l.Insert(l.Len()+1, "x") {
+			t.Errorf("depth %d: inserted out of range", depth)
+		}
+
+		l.Set(5, "five")
+		l.Set(-1, strings.Repeat("y", 100))
+		want[5], want[len(want)-1] = "five", strings.Repeat("y", 100)
+		if got := l.ToSlice(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("depth %d: after sets, list = %v, want %v", depth, got, want)
+		}
+
+		l.DeleteRange(10, 15)
+		l.DeleteRange(0, 2)
+		l.DeleteRange(l.Len()-3, 10)
+		want = append(want[:10], want[25:]...)
+		want = want[2 : len(want)-3]
+		if got := l.ToSlice(); !reflect.DeepEqual(got, want) || l.Len() != len(want) {
+			t.Fatalf("depth %d: after deletions, list = %v (len %d), want %v", depth, got, l.Len(), want)
+		}
+		for i, v := range want {
+			if got, _ := l.Index(i); got != v {
+				t.Errorf("depth %d: Index(%d) = %q, want %q", depth, i, got, v)
+			}
+		}
+	}
+}
+
+func TestList_iterate(t *testing.T) {
+	vs := values(30)
+	l := smallList(1, vs...)
+	var forward, backward []string
+	for it := l.Iterator(0, false); it.Next(); {
+		if it.Index() != len(forward) {
+			t.Fatalf("Index() = %d, want %d", it.Index(), len(forward))
+		}
+		forward = append(forward, it.Value())
+	}
+	for it := l.Iterator(-1, true); it.Next(); {
+		if it.Index() != len(vs)-1-len(backward) {
+			t.Fatalf("Index() = %d, want %d", it.Index(), len(vs)-1-len(backward))
+		}
+		backward = append(backward, it.Value())
+	}
+	reversed := make([]string, len(vs))
+	for i, v := range vs {
+		reversed[len(vs)-1-i] = v
+	}
+	if !reflect.DeepEqual(forward, vs) || !reflect.DeepEqual(backward, reversed) {
+		t.Errorf("iterated %v forward and %v backward", forward, backward)
+	}
+	if NewList().Iterator(0, false).Next() || l.Iterator(30, false).Next() {
+		t.Errorf("iterated past the end of a list")
+	}
+}
+
+func TestIterator_Remove(t *testing.T) {
+	for _, reverse := range []bool{false, true} {
+		vs := values(30)
+		l := smallList(1, vs...)
+		start := 0
+		if reverse {
+			start = -1
+		}
+		var want []string
+		for it := l.Iterator(start, reverse); it.Next(); {
+			// Keep every other element of the last ones only, so that
+			// whole nodes go away too.
+			var i int
+			fmt.Sscanf(it.Value(), "value-%d", &i)
+			if i%2 == 0 || i < 10 {
+				it.Remove()
+				continue
+			}
+			want = append(want, it.Value())
+		}
+		if reverse {
+			for i, j := 0, len(want)-1; i < j; i, j = i+1, j-1 {
+				want[i], want[j] = want[j], want[i]
+			}
+		}
+		if got := l.ToSlice(); !reflect.DeepEqual(got, want) || len(want) != 10 {
+			t.Errorf("reverse %v: after removals, list = %v, want %v", reverse, got, want)
+		}
+
+		for it := l.Iterator(start, reverse); it.Next(); {
+			it.Remove()
+		}
+		if l.Len() != 0 || l.head != nil {
+			t.Errorf("reverse %v: list not empty after removing everything", reverse)
+		}
+	}
+}
+
+func TestList_compress(t *testing.T) {
+	vs := make([]string, 200)
+	for i := range vs {
+		vs[i] = strings.Repeat("compressible ", 4)
+	}
+	l := NewListOptions(256, 2)
+	for _, v := range vs {
+		l.Append(v)
+	}
+	compressed := 0
+	for n, d := l.head, 0; n != nil; n, d = n.next, d+1 {
+		if n.compressed {
+			compressed++
+			if d < 2 || d >= l.nodes-2 {
+				t.Errorf("node %d of %d is compressed, within depth of an end", d, l.nodes)
+			}
+		}
+	}
+	if compressed != l.nodes-4 {
+		t.Errorf("%d nodes of %d compressed, want %d", compressed, l.nodes, l.nodes-4)
+	}
+	if got := l.ToSlice(); !reflect.DeepEqual(got, vs) {
+		t.Errorf("compressed list = %v, want %v", got, vs)
+	}
+}
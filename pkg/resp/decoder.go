@@ -0,0 +1,352 @@
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+const (
+	// maxLineLen is the longest line, such as a simple string or a length
+	// header, a decoder reads.
+	maxLineLen = 64 * 1024
+	// maxBulkLen is the largest string a decoder reads, redis' default
+	// proto-max-bulk-len.
+	maxBulkLen = 512 * 1024 * 1024
+	// maxDepth is the most aggregates a value may be nested in.
+	maxDepth = 128
+	// maxPrealloc is the most elements, or bytes of a string, allocated
+	// upfront from a length read from the stream: past it, room is made
+	// as elements and bytes arrive, so that a length no data follows
+	// cannot make a decoder allocate.
+	maxPrealloc = 1024
+)
+
+// ProtocolError is returned by a Decoder reading a stream that is not valid
+// RESP, or a value past its limits.
+type ProtocolError string
+
+func (e ProtocolError) Error() string {
+	return "Protocol error: " + string(e)
+}
+
+var errTooDeep = ProtocolError("too deeply nested aggregate")
+
+// Decoder reads RESP2 and RESP3 values from a stream, such as the replies
+// of another redis server. Every value is decoded into the reply type it
+// is serialized from, so that what a server replied can be inspected,
+// or relayed as is.
+type Decoder struct {
+	r *bufio.Reader
+}
+
+// NewDecoder returns a decoder reading values from r, buffered unless r is
+// a bufio.Reader already.
+func NewDecoder(r io.Reader) *Decoder {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &Decoder{r: br}
+}
+
+// Decode reads the next value from the stream. Attributes are returned as
+// an AttributeReply wrapping the value that follows them. An error reply is
+// returned as an ErrorReply or a BulkErrorReply value, not as an error:
+// errors are only returned when the stream cannot be read or decoded.
+func (d *Decoder) Decode() (Reply, error) {
+	return d.decode(0)
+}
+
+// decode reads the next value, nested in depth aggregates.
+func (d *Decoder) decode(depth int) (Reply, error) {
+	line, err := d.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, ProtocolError("empty type line")
+	}
+
+	t, v := line[0], string(line[1:])
+	switch t {
+	case '+':
+		return StatusReply(v), nil
+	case '-':
+		return ErrorReply(v), nil
+	case ':':
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, ProtocolError(fmt.Sprintf("invalid integer %q", v))
+		}
+		return IntegerReply(i), nil
+	case '_':
+		if v != "" {
+			return nil, ProtocolError(fmt.Sprintf("invalid null %q", v))
+		}
+		return NullReply{}, nil
+	case '#':
+		switch v {
+		case "t":
+			return BooleanReply(true), nil
+		case "f":
+			return BooleanReply(false), nil
+		}
+		return nil, ProtocolError(fmt.Sprintf("invalid boolean %q", v))
+	case ',':
+		return parseDouble(v)
+	case '(':
+		n, ok := new(big.Int).SetString(v, 10)
+		if !ok {
+			return nil, ProtocolError(fmt.Sprintf("invalid big number %q", v))
+		}
+		return BigNumberReply{n}, nil
+	case '$', '!', '=':
+		return d.decodeString(t, v)
+	case '*', '~', '>':
+		if depth >= maxDepth {
+			return nil, errTooDeep
+		}
+		return d.decodeAggregate(t, v, depth+1)
+	case '%', '|':
+		if depth >= maxDepth {
+			return nil, errTooDeep
+		}
+		m, err := d.decodeMap(v, depth+1)
+		if err != nil || t == '%' {
+			return m, err
+		}
+		// The value attributes apply to counts as nested in them, or
+		// attributes could follow each other without bound.
+		r, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return AttributeReply{Attributes: m, Reply: r}, nil
+	}
+	return nil, ProtocolError(fmt.Sprintf("unknown type '%c'", t))
+}
+
+// readLine reads a line terminated by CRLF, and returns it without the CRLF.
+func (d *Decoder) readLine() ([]byte, error) {
+	line, err := d.r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		// The line is longer than what the reader buffers, it is accumulated.
+		buf := bytes.Clone(line)
+		for err == bufio.ErrBufferFull && len(buf) <= maxLineLen {
+			line, err = d.r.ReadSlice('\n')
+			buf = append(buf, line...)
+		}
+		if err == bufio.ErrBufferFull {
+			return nil, ProtocolError("line too long")
+		}
+		line = buf
+	}
+	if err != nil {
+		if err == io.EOF && len(line) > 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, ProtocolError("expected CRLF")
+	}
+	return line[:len(line)-2], nil
+}
+
+// readLength parses the length of a string or an aggregate. It returns -1
+// for a streamed value, whose length is not known upfront.
+func readLength(v string) (int, error) {
+	if v == "?" {
+		return -1, nil
+	}
+	l, err := strconv.Atoi(v)
+	if err != nil || l < -1 {
+		return 0, ProtocolError(fmt.Sprintf("invalid length %q", v))
+	}
+	return l, nil
+}
+
+func (d *Decoder) decodeString(t byte, v string) (Reply, error) {
+	l, err := readLength(v)
+	if err != nil {
+		return nil, err
+	}
+	var s []byte
+	switch {
+	case l == -1 && v == "?":
+		if t != '$' {
+			return nil, ProtocolError(fmt.Sprintf("invalid length %q", v))
+		}
+		s, err = d.readChunks()
+	case l == -1:
+		if t != '$' {
+			return nil, ProtocolError(fmt.Sprintf("invalid length %q", v))
+		}
+		return NullBulkReply{}, nil
+	default:
+		s, err = d.readBulk(l)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch t {
+	case '!':
+		return BulkErrorReply(s), nil
+	case '=':
+		if len(s) < 4 || s[3] != ':' {
+			return nil, ProtocolError(fmt.Sprintf("invalid verbatim string %q", s))
+		}
+		return VerbatimReply{Format: string(s[:3]), Text: string(s[4:])}, nil
+	}
+	return BulkReply(s), nil
+}
+
+// readBulk reads a payload of l bytes followed by a CRLF.
+func (d *Decoder) readBulk(l int) ([]byte, error) {
+	if l > maxBulkLen {
+		return nil, ProtocolError("invalid bulk length")
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, min(l+2, maxPrealloc)))
+	if _, err := io.CopyN(buf, d.r, int64(l+2)); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	b := buf.Bytes()
+	if b[l] != '\r' || b[l+1] != '\n' {
+		return nil, ProtocolError("expected CRLF after bulk string")
+	}
+	return b[:l], nil
+}
+
+// readChunks reads the ";<length>\r\n<data>\r\n" chunks of a streamed
+// string, up to the final empty chunk.
+func (d *Decoder) readChunks() ([]byte, error) {
+	var s []byte
+	for {
+		line, err := d.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != ';' {
+			return nil, ProtocolError(fmt.Sprintf("expected ';', got %q", line))
+		}
+		l, err := strconv.Atoi(string(line[1:]))
+		if err != nil || l < 0 {
+			return nil, ProtocolError(fmt.Sprintf("invalid chunk length %q", line[1:]))
+		}
+		if l == 0 {
+			return s, nil
+		}
+		c, err := d.readBulk(l)
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, c...)
+	}
+}
+
+// decodeElements decodes the n elements of an aggregate nested in depth
+// others, or the elements up to the end marker of a streamed aggregate
+// when n is -1.
+func (d *Decoder) decodeElements(n, depth int) ([]Reply, error) {
+	res := make([]Reply, 0, min(max(n, 0), maxPrealloc))
+	for n < 0 || len(res) < n {
+		if n < 0 {
+			b, err := d.r.Peek(1)
+			if err != nil {
+				return nil, err
+			}
+			if b[0] == '.' {
+				if line, err := d.readLine(); err != nil || len(line) != 1 {
+					return nil, ProtocolError("invalid end of streamed aggregate")
+				}
+				return res, nil
+			}
+		}
+		r, err := d.decode(depth)
+		if err != nil {
+			if err == io.EOF {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		res = append(res, r)
+	}
+	return res, nil
+}
+
+func (d *Decoder) decodeAggregate(t byte, v string, depth int) (Reply, error) {
+	n, err := readLength(v)
+	if err != nil {
+		return nil, err
+	}
+	if n == -1 && v != "?" {
+		if t != '*' {
+			return nil, ProtocolError(fmt.Sprintf("invalid length %q", v))
+		}
+		return NullArrayReply{}, nil
+	}
+	res, err := d.decodeElements(n, depth)
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case '~':
+		return SetReply(res), nil
+	case '>':
+		return PushReply(res), nil
+	}
+	return ArrayReply(res), nil
+}
+
+func (d *Decoder) decodeMap(v string, depth int) (MapReply, error) {
+	n, err := readLength(v)
+	if err != nil {
+		return nil, err
+	}
+	if n == -1 && v != "?" {
+		return nil, ProtocolError(fmt.Sprintf("invalid length %q", v))
+	}
+	if n > math.MaxInt/2 {
+		return nil, ProtocolError(fmt.Sprintf("invalid length %q", v))
+	}
+	if n > 0 {
+		n *= 2
+	}
+	res, err := d.decodeElements(n, depth)
+	if err != nil {
+		return nil, err
+	}
+	if len(res)%2 != 0 {
+		return nil, ProtocolError("map with a key but no value")
+	}
+	m := make(MapReply, 0, len(res)/2)
+	for i := 0; i < len(res); i += 2 {
+		m = append(m, KeyValue{Key: res[i], Value: res[i+1]})
+	}
+	return m, nil
+}
+
+func parseDouble(v string) (Reply, error) {
+	switch v {
+	case "inf":
+		return DoubleReply(math.Inf(1)), nil
+	case "-inf":
+		return DoubleReply(math.Inf(-1)), nil
+	case "nan", "-nan":
+		return DoubleReply(math.NaN()), nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, ProtocolError(fmt.Sprintf("invalid double %q", v))
+	}
+	return DoubleReply(f), nil
+}
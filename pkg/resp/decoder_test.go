@@ -0,0 +1,110 @@
+package resp
+
+import (
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecoder(t *testing.T) {
+	type testCase struct {
+		name string
+		data string
+		want Reply
+	}
+	tests := []testCase{
+		{"simple-string", "+OK\r\n", StatusReply("OK")},
+		{"simple-error", "-ERR unknown\r\n", ErrorReply("ERR unknown")},
+		{"integer", ":-12\r\n", IntegerReply(-12)},
+		{"bulk-string", "$7\r\na\r\nb\x00cd\r\n", BulkReply("a\r\nb\x00cd")},
+		{"bulk-string-empty", "$0\r\n\r\n", BulkReply("")},
+		{"bulk-string-long", "$5000\r\n" + strings.Repeat("x", 5000) + "\r\n", BulkReply(strings.Repeat("x", 5000))},
+		{"null-bulk-string", "$-1\r\n", NullBulkReply{}},
+		{"null-array", "*-1\r\n", NullArrayReply{}},
+		{"null", "_\r\n", NullReply{}},
+		{"array", "*2\r\n:1\r\n*1\r\n+a\r\n", ArrayReply{IntegerReply(1), ArrayReply{StatusReply("a")}}},
+		{"array-empty", "*0\r\n", ArrayReply{}},
+		{"boolean", "#t\r\n", BooleanReply(true)},
+		{"double", ",1.5e3\r\n", DoubleReply(1500)},
+		{"double-inf", ",-inf\r\n", DoubleReply(math.Inf(-1))},
+		{"big-number", "(3492890328409238509324850943850943825024385\r\n", BigNumberReply{bigInt("3492890328409238509324850943850943825024385")}},
+		{"bulk-error", "!21\r\nSYNTAX invalid syntax\r\n", BulkErrorReply("SYNTAX invalid syntax")},
+		{"verbatim-string", "=15\r\ntxt:Some string\r\n", VerbatimReply{"txt", "Some string"}},
+		{"map", "%2\r\n+first\r\n:1\r\n+second\r\n:2\r\n", MapReply{{StatusReply("first"), IntegerReply(1)}, {StatusReply("second"), IntegerReply(2)}}},
+		{"set", "~2\r\n+a\r\n+b\r\n", SetReply{StatusReply("a"), StatusReply("b")}},
+		{"attribute", "|1\r\n+ttl\r\n:3\r\n*1\r\n:2\r\n", AttributeReply{MapReply{{StatusReply("ttl"), IntegerReply(3)}}, ArrayReply{IntegerReply(2)}}},
+		{"push", ">2\r\n+message\r\n$2\r\nhi\r\n", PushReply{StatusReply("message"), BulkReply("hi")}},
+		{"streamed-string", "$?\r\n;4\r\nHell\r\n;1\r\no\r\n;0\r\n", BulkReply("Hello")},
+		{"streamed-array", "*?\r\n:1\r\n:2\r\n.\r\n", ArrayReply{IntegerReply(1), IntegerReply(2)}},
+		{"streamed-map", "%?\r\n+a\r\n:1\r\n.\r\n", MapReply{{StatusReply("a"), IntegerReply(1)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDecoder(iotest.OneByteReader(strings.NewReader(tt.data)))
+			got, err := d.Decode()
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() = %#v, want %#v", got, tt.want)
+			}
+			if _, err := d.Decode(); err != io.EOF {
+				t.Errorf("Decode() at end of stream error = %v, want %v", err, io.EOF)
+			}
+		})
+	}
+}
+
+func TestDecoderErrors(t *testing.T) {
+	for _, data := range []string{
+		"?\r\n",
+		":12a\r\n",
+		"+OK\n",
+		"#x\r\n",
+		"$3\r\nabcd\r\n",
+		"$-2\r\n",
+		"=3\r\ntxt\r\n",
+		"%1\r\n+a\r\n.\r\n",
+		"*2\r\n:1\r\n",
+		"$5\r\nab",
+		"%4611686018427387904\r\n",
+		strings.Repeat("*1\r\n", maxDepth+1) + ":1\r\n",
+		strings.Repeat("|0\r\n", maxDepth+1) + ":1\r\n",
+	} {
+		if got, err := NewDecoder(strings.NewReader(data)).Decode(); err == nil {
+			t.Errorf("Decode(%q) = %#v, want an error", data, got)
+		}
+	}
+}
+
+func TestDecoderLimits(t *testing.T) {
+	// Values nested as deep as allowed are decoded.
+	data := strings.Repeat("*1\r\n", maxDepth) + ":1\r\n"
+	if _, err := NewDecoder(strings.NewReader(data)).Decode(); err != nil {
+		t.Errorf("Decode() of %d nested arrays error = %v", maxDepth, err)
+	}
+
+	// Lengths that no data follows do not get allocated.
+	for _, data := range []string{"*2147483647\r\n:1\r\n", "%1073741823\r\n", "$536870912\r\nab"} {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, err := NewDecoder(strings.NewReader(data)).Decode()
+		runtime.ReadMemStats(&after)
+		if err != io.ErrUnexpectedEOF {
+			t.Errorf("Decode(%q) error = %v, want %v", data, err, io.ErrUnexpectedEOF)
+		}
+		if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+			t.Errorf("Decode(%q) allocated %d bytes", data, n)
+		}
+	}
+}
+
+func bigInt(s string) *big.Int {
+	n, _ := new(big.Int).SetString(s, 10)
+	return n
+}
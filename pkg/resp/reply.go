@@ -0,0 +1,97 @@
+// Package resp implements the values of the RESP2 and RESP3 protocols
+// redis servers speak, and a decoder reading them from a stream.
+package resp
+
+import "math/big"
+
+// Reply is a RESP value, as a command replies with. Every reply type maps
+// to exactly one RESP type.
+type Reply interface {
+	reply()
+}
+
+// StatusReply is a simple string such as OK or PONG.
+type StatusReply string
+
+// BulkReply is a binary-safe string.
+type BulkReply string
+
+// NullReply is a null that is neither a string nor an array,
+// as found in RESP3 replies.
+type NullReply struct{}
+
+// NullBulkReply is the null reply of commands replying with a string.
+type NullBulkReply struct{}
+
+// NullArrayReply is the null reply of commands replying with an array.
+type NullArrayReply struct{}
+
+type IntegerReply int64
+
+type DoubleReply float64
+
+type BooleanReply bool
+
+type ArrayReply []Reply
+
+// MapReply is a map kept in the order its pairs are replied in.
+type MapReply []KeyValue
+
+type KeyValue struct {
+	Key   Reply
+	Value Reply
+}
+
+// SetReply is an unordered collection of unique elements.
+type SetReply []Reply
+
+// PushReply is out of band data, such as a pubsub message.
+type PushReply []Reply
+
+// VerbatimReply is a string meant to be shown to the user as is.
+// Format is a three characters hint of its content, as "txt" or "mkd".
+type VerbatimReply struct {
+	Format string
+	Text   string
+}
+
+type BigNumberReply struct {
+	*big.Int
+}
+
+// AttributeReply is a reply along with auxiliary data about it, such as
+// the popularity of the keys it was read from.
+// Clients that do not expect attributes only see the reply.
+type AttributeReply struct {
+	Attributes MapReply
+	Reply      Reply
+}
+
+// ErrorReply is an error replied as part of another reply,
+// as the result of one of the commands of a transaction.
+type ErrorReply string
+
+func (e ErrorReply) Error() string { return string(e) }
+
+// BulkErrorReply is an error whose message may span several lines.
+type BulkErrorReply string
+
+func (e BulkErrorReply) Error() string { return string(e) }
+
+func (StatusReply) reply()    {}
+func (BulkReply) reply()      {}
+func (NullReply) reply()      {}
+func (NullBulkReply) reply()  {}
+func (NullArrayReply) reply() {}
+func (IntegerReply) reply()   {}
+func (DoubleReply) reply()    {}
+func (BooleanReply) reply()   {}
+func (ArrayReply) reply()     {}
+func (MapReply) reply()       {}
+func (SetReply) reply()       {}
+func (PushReply) reply()      {}
+func (VerbatimReply) reply()  {}
+func (BigNumberReply) reply() {}
+func (AttributeReply) reply() {}
+func (ErrorReply) reply()     {}
+func (BulkErrorReply) reply() {}